@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/audit"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Audited actions. Admin actions are recorded with the admin as the actor
+// and the user or chirp they acted on as the target.
+const (
+	auditLogin            = "auth.login"
+	auditLoginFailed      = "auth.login_failed"
+	auditTokenRevoked     = "auth.token_revoked"
+	auditUserCreated      = "user.created"
+	auditEmailChanged     = "user.email_changed"
+	auditPasswordChanged  = "user.password_changed"
+	auditDeletionRequest  = "user.deletion_requested"
+	auditChirpyRedGranted = "user.chirpy_red_granted"
+	auditChirpyRedRevoked = "user.chirpy_red_revoked"
+	auditChirpDeleted     = "chirp.deleted"
+	auditChirpHidden      = "chirp.hidden"
+	auditUserSuspended    = "admin.user_suspended"
+	auditUserUnsuspended  = "admin.user_unsuspended"
+	auditUserBanned       = "admin.user_banned"
+	auditUserUnbanned     = "admin.user_unbanned"
+	auditUserLoggedOut    = "admin.user_logged_out"
+	auditRoleChanged      = "admin.role_changed"
+
+	auditTargetUser  = "user"
+	auditTargetChirp = "chirp"
+
+	requestIDHeader = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+type requestIDKey struct{}
+
+// middlewareRequestID gives every request an ID, echoed in the response
+// and recorded with any audit events it causes. A well-formed ID from the
+// client or a proxy in front of us is kept, so requests can be traced
+// across services.
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// auditRecord is what a handler knows about an audited action; the rest
+// of the entry comes from the request.
+type auditRecord struct {
+	// ActorID is uuid.Nil when nobody is logged in, such as for a failed
+	// login or a Polka webhook.
+	ActorID    uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   uuid.UUID
+	Metadata   map[string]any
+}
+
+// recordAudit appends an entry to the audit log as part of the
+// transaction q is in, so the entry is committed exactly when the action it
+// records is. The chain stays locked until that transaction ends.
+func recordAudit(r *http.Request, q *database.Queries, rec auditRecord) error {
+	metadata := []byte("{}")
+	if rec.Metadata != nil {
+		var err error
+		metadata, err = json.Marshal(rec.Metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	entry := audit.Entry{
+		CreatedAt:  time.Now(),
+		Action:     rec.Action,
+		TargetType: rec.TargetType,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		RequestID:  requestID(r),
+		Metadata:   metadata,
+	}
+	if rec.ActorID != uuid.Nil {
+		entry.ActorID = rec.ActorID.String()
+	}
+	if rec.TargetID != uuid.Nil {
+		entry.TargetID = rec.TargetID.String()
+	}
+
+	return appendAuditEntry(r.Context(), q, entry)
+}
+
+// recordAuditEvent records something that happened without changing
+// anything else, such as a failed login, in a transaction of its own. The
+// entry is written even if the client has gone away.
+func (cfg *apiConfig) recordAuditEvent(r *http.Request, rec auditRecord) error {
+	ctx := context.WithoutCancel(r.Context())
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = recordAudit(r.WithContext(ctx), cfg.db.WithTx(tx), rec)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appendAuditEntry chains entry onto the latest one in the log and stores
+// it. Writers take turns, so two entries never chain onto the same one.
+func appendAuditEntry(ctx context.Context, q *database.Queries, entry audit.Entry) error {
+	err := q.LockAuditChain(ctx)
+	if err != nil {
+		return err
+	}
+
+	var prev *audit.Entry
+	latest, err := q.GetLatestAuditEvent(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		e := auditEntry(latest)
+		prev = &e
+	}
+
+	entry = audit.Chain(entry, prev)
+	actorID := uuid.NullUUID{}
+	if entry.ActorID != "" {
+		actorID.UUID, err = uuid.Parse(entry.ActorID)
+		if err != nil {
+			return err
+		}
+		actorID.Valid = true
+	}
+
+	return q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		ID:         entry.Seq,
+		CreatedAt:  entry.CreatedAt,
+		ActorID:    actorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Ip:         entry.IP,
+		UserAgent:  entry.UserAgent,
+		RequestID:  entry.RequestID,
+		Metadata:   entry.Metadata,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	})
+}
+
+func auditEntry(e database.AuditEvent) audit.Entry {
+	entry := audit.Entry{
+		Seq:        e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.Ip,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Metadata:   e.Metadata,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+	if e.ActorID.Valid {
+		entry.ActorID = e.ActorID.UUID.String()
+	}
+	return entry
+}
+
+// clientIP is the address the request came from. Chirpy doesn't trust
+// X-Forwarded-For, so behind a proxy this is the proxy's address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/google/uuid"
+)
+
+type Entity struct {
+	Type   chirptext.EntityType `json:"type"`
+	Text   string               `json:"text"`
+	Start  int                  `json:"start"`
+	End    int                  `json:"end"`
+	UserID *uuid.UUID           `json:"user_id,omitempty"`
+}
+
+// viewerID returns the ID of the user making the request, or uuid.Nil when
+// the request carries no valid access token. It's for endpoints that work
+// without authentication but personalise their response for a known user.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// ChirpEmbed is the original of a rechirp or a quote. If the original has
+// since been deleted or hidden by moderators only its ID is known, and the
+// embed renders as {"id": ..., "deleted": true} so clients can show a
+// placeholder.
+type ChirpEmbed struct {
+	*Chirp
+	ID      uuid.UUID `json:"id"`
+	Deleted bool      `json:"deleted"`
+}
+
+// userRelations loads who userID has blocked or muted and who has blocked
+// them. Anonymous users have none.
+func userRelations(ctx context.Context, q *database.Queries, userID uuid.UUID) (feed.Relations, error) {
+	relations := feed.Relations{}
+	if userID == uuid.Nil {
+		return relations, nil
+	}
+
+	rows, err := q.GetUserRelations(ctx, userID)
+	if err != nil {
+		return feed.Relations{}, err
+	}
+	for _, row := range rows {
+		relations.Add(feed.Kind(row.Kind), row.UserID)
+	}
+	return relations, nil
+}
+
+// visibleChirpsResponse is chirpsResponse for listings, applying viewerID's
+// filters. It leaves out chirps the viewer isn't in the audience for,
+// chirps by or sharing chirps by users who they've blocked or muted or who
+// blocked them, and chirps matching a muted word set to hide. Those
+// matching a muted word set to collapse are marked as filtered.
+func (cfg *apiConfig) visibleChirpsResponse(ctx context.Context, dbChirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	relations, err := userRelations(ctx, cfg.db, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	wordFilter, err := userWordFilter(ctx, cfg.db, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := chirpViewer(ctx, cfg.db, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	dbChirps, err = viewableChirps(ctx, cfg.db, viewer, dbChirps)
+	if err != nil {
+		return nil, err
+	}
+
+	chirps, err := cfg.chirpsResponse(ctx, dbChirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []Chirp{}
+	for _, chirp := range chirps {
+		authorIDs := []uuid.UUID{chirp.UserID}
+		for _, embed := range []*ChirpEmbed{chirp.RechirpOf, chirp.QuotedChirp} {
+			if embed != nil && embed.Chirp != nil {
+				authorIDs = append(authorIDs, embed.Chirp.UserID)
+			}
+		}
+		if relations.Hides(authorIDs...) {
+			continue
+		}
+
+		chirp.Filtered = matchMutedWords(wordFilter, chirp)
+		if chirp.Filtered != nil && chirp.Filtered.Action == feed.ActionHide {
+			continue
+		}
+		visible = append(visible, chirp)
+	}
+	return visible, nil
+}
+
+// chirpsResponse converts database chirps into API chirps, looking up
+// everything the response needs in batches rather than once per chirp.
+// Fields that depend on who is asking are only filled in when viewerID is
+// not uuid.Nil. Originals the viewer can't see are embedded as deleted.
+func (cfg *apiConfig) chirpsResponse(ctx context.Context, dbChirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	chirps, err := cfg.buildChirps(ctx, dbChirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range dbChirps {
+		if chirp.RechirpOfID.Valid {
+			originalIDs = append(originalIDs, chirp.RechirpOfID.UUID)
+		}
+		if chirp.QuotedChirpID.Valid {
+			originalIDs = append(originalIDs, chirp.QuotedChirpID.UUID)
+		}
+	}
+	if len(originalIDs) == 0 {
+		return chirps, nil
+	}
+
+	dbOriginals, err := cfg.db.GetChirpsByIDs(ctx, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+	viewer, err := chirpViewer(ctx, cfg.db, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	dbOriginals, err = viewableChirps(ctx, cfg.db, viewer, dbOriginals)
+	if err != nil {
+		return nil, err
+	}
+	// Originals are embedded one level deep: a quote inside a rechirp shows
+	// its own quoted_chirp_id but not the chirp behind it.
+	originalChirps, err := cfg.buildChirps(ctx, dbOriginals, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	originals := map[uuid.UUID]*Chirp{}
+	for i := range originalChirps {
+		originals[originalChirps[i].ID] = &originalChirps[i]
+	}
+	embed := func(id uuid.UUID) *ChirpEmbed {
+		original, ok := originals[id]
+		return &ChirpEmbed{
+			Chirp:   original,
+			ID:      id,
+			Deleted: !ok,
+		}
+	}
+
+	for i, dbChirp := range dbChirps {
+		if dbChirp.RechirpOfID.Valid {
+			chirps[i].RechirpOf = embed(dbChirp.RechirpOfID.UUID)
+		}
+		if dbChirp.QuotedChirpID.Valid {
+			chirps[i].QuotedChirp = embed(dbChirp.QuotedChirpID.UUID)
+		}
+	}
+
+	return chirps, nil
+}
+
+func (cfg *apiConfig) buildChirps(ctx context.Context, dbChirps []database.Chirp, viewerID uuid.UUID) ([]Chirp, error) {
+	chirpIDs := make([]uuid.UUID, 0, len(dbChirps))
+	for _, chirp := range dbChirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	mentions, err := cfg.db.GetChirpMentionsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	type mentionKey struct {
+		chirpID uuid.UUID
+		start   int
+	}
+	mentionedUsers := map[mentionKey]uuid.UUID{}
+	for _, mention := range mentions {
+		if mention.UserID.Valid {
+			mentionedUsers[mentionKey{mention.ChirpID, int(mention.StartIndex)}] = mention.UserID.UUID
+		}
+	}
+
+	dbMedia, err := cfg.db.GetMediaByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	chirpMedia := map[uuid.UUID][]Media{}
+	for _, m := range dbMedia {
+		chirpMedia[m.ChirpID.UUID] = append(chirpMedia[m.ChirpID.UUID], mediaResponse(m))
+	}
+
+	polls, err := cfg.pollsResponse(ctx, chirpIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	likedChirps := map[uuid.UUID]bool{}
+	rechirpedChirps := map[uuid.UUID]bool{}
+	if viewerID != uuid.Nil {
+		likedChirpIDs, err := cfg.db.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirpID := range likedChirpIDs {
+			likedChirps[chirpID] = true
+		}
+
+		rechirpedChirpIDs, err := cfg.db.GetRechirpedChirpIDs(ctx, database.GetRechirpedChirpIDsParams{
+			UserID:   viewerID,
+			ChirpIds: chirpIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirpID := range rechirpedChirpIDs {
+			rechirpedChirps[chirpID] = true
+		}
+	}
+
+	chirps := make([]Chirp, 0, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		entities := []Entity{}
+		for _, entity := range chirptext.ParseEntities(dbChirp.Body) {
+			e := Entity{
+				Type:  entity.Type,
+				Text:  entity.Text,
+				Start: entity.Start,
+				End:   entity.End,
+			}
+			if userID, ok := mentionedUsers[mentionKey{dbChirp.ID, entity.Start}]; ok {
+				e.UserID = &userID
+			}
+			entities = append(entities, e)
+		}
+
+		media := chirpMedia[dbChirp.ID]
+		if media == nil {
+			media = []Media{}
+		}
+
+		chirp := Chirp{
+			ID:           dbChirp.ID,
+			CreatedAt:    dbChirp.CreatedAt,
+			UpdatedAt:    dbChirp.UpdatedAt,
+			Body:         dbChirp.Body,
+			UserID:       dbChirp.UserID,
+			Edited:       dbChirp.UpdatedAt.After(dbChirp.CreatedAt),
+			Entities:     entities,
+			Media:        media,
+			Poll:         polls[dbChirp.ID],
+			LikeCount:    dbChirp.LikeCount,
+			RechirpCount: dbChirp.RechirpCount,
+			QuoteCount:   dbChirp.QuoteCount,
+			Visibility:   feed.Visibility(dbChirp.Visibility),
+		}
+		if dbChirp.QuotedChirpID.Valid {
+			chirp.QuotedChirpID = &dbChirp.QuotedChirpID.UUID
+		}
+		if dbChirp.ReplyToID.Valid {
+			chirp.ReplyToID = &dbChirp.ReplyToID.UUID
+		}
+		if dbChirp.DeletedAt.Valid {
+			chirp.DeletedAt = &dbChirp.DeletedAt.Time
+		}
+		if dbChirp.HiddenAt.Valid {
+			chirp.HiddenAt = &dbChirp.HiddenAt.Time
+		}
+		if viewerID != uuid.Nil {
+			likedByMe := likedChirps[dbChirp.ID]
+			chirp.LikedByMe = &likedByMe
+			rechirpedByMe := rechirpedChirps[dbChirp.ID]
+			chirp.RechirpedByMe = &rechirpedByMe
+		}
+		chirps = append(chirps, chirp)
+	}
+
+	return chirps, nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, dbChirp database.Chirp, viewerID uuid.UUID) (Chirp, error) {
+	chirps, err := cfg.chirpsResponse(ctx, []database.Chirp{dbChirp}, viewerID)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return chirps[0], nil
+}
+
+// originalChirpID returns the ID of the chirp a rechirp points at, or the
+// chirp's own ID otherwise. Likes, quotes and rechirps always target the
+// original rather than somebody's rechirp of it.
+func originalChirpID(chirp database.Chirp) uuid.UUID {
+	if chirp.RechirpOfID.Valid {
+		return chirp.RechirpOfID.UUID
+	}
+	return chirp.ID
+}
+
+// updateShareCounts adds delta to the rechirp or quote count of the chirp
+// that chirp shares, if it shares one.
+func updateShareCounts(ctx context.Context, q *database.Queries, chirp database.Chirp, delta int32) error {
+	if chirp.RechirpOfID.Valid {
+		err := q.UpdateRechirpCount(ctx, database.UpdateRechirpCountParams{
+			ID:    chirp.RechirpOfID.UUID,
+			Delta: delta,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if chirp.QuotedChirpID.Valid {
+		err := q.UpdateQuoteCount(ctx, database.UpdateQuoteCountParams{
+			ID:    chirp.QuotedChirpID.UUID,
+			Delta: delta,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveChirpEntities stores the hashtags and mentions in a chirp's body so
+// they can be searched, resolving each mention to the user it names. It
+// should run in the same transaction that writes the chirp.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	entities := chirptext.ParseEntities(chirp.Body)
+
+	usernames := []string{}
+	for _, entity := range entities {
+		if entity.Type == chirptext.EntityMention {
+			usernames = append(usernames, strings.ToLower(entity.Text))
+		}
+	}
+	userIDs := map[string]uuid.UUID{}
+	if len(usernames) > 0 {
+		users, err := q.GetUsersByUsernames(ctx, usernames)
+		if err != nil {
+			return err
+		}
+		// Mentions across a block stay plain text, so they neither link
+		// to the user nor notify them.
+		relations, err := userRelations(ctx, q, chirp.UserID)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if !relations.Blocked(user.ID) {
+				userIDs[user.Username.String] = user.ID
+			}
+		}
+	}
+
+	for _, entity := range entities {
+		switch entity.Type {
+		case chirptext.EntityHashtag:
+			err := q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+				ChirpID:    chirp.ID,
+				Tag:        chirptext.NormalizeTag(entity.Text),
+				StartIndex: int32(entity.Start),
+				EndIndex:   int32(entity.End),
+			})
+			if err != nil {
+				return err
+			}
+		case chirptext.EntityMention:
+			username := strings.ToLower(entity.Text)
+			userID, ok := userIDs[username]
+			err := q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+				ChirpID:    chirp.ID,
+				Username:   username,
+				UserID:     uuid.NullUUID{UUID: userID, Valid: ok},
+				StartIndex: int32(entity.Start),
+				EndIndex:   int32(entity.End),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
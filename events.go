@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/events"
+	"github.com/google/uuid"
+)
+
+// Domain event types. They're written to the outbox in the same
+// transaction as the change they describe, then relayed to cfg.events.
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+	eventUserFollowed = "user.followed"
+	eventUserUpgraded = "user.upgraded"
+
+	outboxChannel = "outbox"
+
+	outboxBatchSize = 100
+	// maxOutboxAttempts is how many times an event is relayed before it's
+	// left in the outbox, with its last error, for someone to look at.
+	maxOutboxAttempts  = 10
+	maxOutboxBackoff   = time.Hour
+	outboxPollInterval = 5 * time.Second
+	outboxRetention    = 7 * 24 * time.Hour
+)
+
+type chirpCreatedEvent struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type chirpDeletedEvent struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+type userFollowedEvent struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+}
+
+type userUpgradedEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// enqueueEvent writes a domain event to the outbox. q should be in the
+// same transaction as the change the event describes, so the event exists
+// if and only if the change commits.
+func enqueueEvent(ctx context.Context, q *database.Queries, eventType string, payload any) error {
+	e, err := events.New(eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	err = q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		EventType: e.Type,
+		Payload:   e.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyOutbox(ctx)
+}
+
+func enqueueChirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	return enqueueEvent(ctx, q, eventChirpCreated, chirpCreatedEvent{
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserID,
+	})
+}
+
+func enqueueChirpDeleted(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	return enqueueEvent(ctx, q, eventChirpDeleted, chirpDeletedEvent{
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserID,
+	})
+}
+
+// announceChirpCreated puts a chirp on the chirp stream and tells webhooks
+// about it. The chirp stream is public, so chirps with a narrower audience
+// stay off it.
+func announceChirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	public, err := isPublicChirp(ctx, q, chirp)
+	if err != nil {
+		return err
+	}
+	if public {
+		err = notifyChirpEvent(ctx, q, eventChirpCreated, chirp)
+		if err != nil {
+			return err
+		}
+	}
+	return enqueueChirpCreated(ctx, q, chirp)
+}
+
+// announceChirpDeleted takes a chirp back off the chirp stream, if it went
+// out there, and tells webhooks it's gone. public is whether the chirp was
+// public before it was removed; anything else would tell anonymous
+// listeners it existed.
+func announceChirpDeleted(ctx context.Context, q *database.Queries, chirp database.Chirp, public bool) error {
+	if public {
+		err := notifyChirpEvent(ctx, q, eventChirpDeleted, chirp)
+		if err != nil {
+			return err
+		}
+	}
+	return enqueueChirpDeleted(ctx, q, chirp)
+}
+
+// wakeOutbox tells the relay there are new events without waiting for its
+// next poll. It never blocks; one pending wake-up covers any number.
+func (cfg *apiConfig) wakeOutbox() {
+	select {
+	case cfg.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// relayOutbox relays outbox events to cfg.events whenever listenEvents
+// hears that some were committed, and every interval in case it missed
+// them or an earlier attempt failed.
+func (cfg *apiConfig) relayOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := cfg.relayOutboxEvents(ctx)
+			if err != nil {
+				log.Printf("Error relaying outbox events: %v", err)
+			}
+			if err != nil || n < outboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cfg.outboxWake:
+		}
+	}
+}
+
+// relayOutboxEvents relays one batch of events, oldest first. Rows are
+// claimed with FOR UPDATE SKIP LOCKED, so replicas relay different events.
+// Delivery is at least once: if the process dies after a subscriber has run
+// but before the event is marked processed, it's relayed again with the
+// same key.
+func (cfg *apiConfig) relayOutboxEvents(ctx context.Context) (int, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	rows, err := qtx.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		MaxAttempts: maxOutboxAttempts,
+		MaxResults:  outboxBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		err := cfg.events.Publish(ctx, events.Event{
+			Key:     row.ID.String(),
+			Type:    row.EventType,
+			Payload: row.Payload,
+		})
+		if err != nil {
+			log.Printf("Error relaying %s event %s: %v", row.EventType, row.ID, err)
+			err = qtx.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+				ID:            row.ID,
+				NextAttemptAt: time.Now().Add(outboxBackoff(int(row.Attempts) + 1)).UTC(),
+				LastError:     sql.NullString{String: err.Error(), Valid: true},
+			})
+		} else {
+			err = qtx.MarkOutboxEventProcessed(ctx, row.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// outboxBackoff returns how long to wait before relaying an event again
+// after the given number of failed attempts: a second, doubling each time.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempts && backoff < maxOutboxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxOutboxBackoff)
+}
+
+// purgeProcessedOutbox deletes events that were relayed more than
+// outboxRetention ago.
+func (cfg *apiConfig) purgeProcessedOutbox(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.db.PurgeProcessedOutboxEvents(ctx, sql.NullTime{
+			Time:  time.Now().Add(-outboxRetention).UTC(),
+			Valid: true,
+		})
+		if err != nil {
+			log.Printf("Error purging outbox: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d processed outbox events", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
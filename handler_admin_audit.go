@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/audit"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditEventsLimit = 50
+	maxAuditEventsLimit     = 500
+	auditVerifyBatchSize    = 1000
+)
+
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorID    *uuid.UUID      `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type,omitempty"`
+	TargetID   string          `json:"target_id,omitempty"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	RequestID  string          `json:"request_id"`
+	Metadata   json.RawMessage `json:"metadata"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+func auditEventResponse(e database.AuditEvent) AuditEvent {
+	event := AuditEvent{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.Ip,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Metadata:   e.Metadata,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+	if e.ActorID.Valid {
+		event.ActorID = &e.ActorID.UUID
+	}
+	return event
+}
+
+// auditEventsParams reads the filters for handlerAdminAuditList from the
+// query string. Times are RFC 3339; before is the next_before of the
+// previous page.
+func auditEventsParams(r *http.Request) (database.GetAuditEventsParams, error) {
+	query := r.URL.Query()
+	params := database.GetAuditEventsParams{}
+
+	limit, err := queryLimit(r, defaultAuditEventsLimit, maxAuditEventsLimit)
+	if err != nil {
+		return params, err
+	}
+	params.MaxResults = int32(limit)
+
+	if s := query.Get("actor_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return params, fmt.Errorf("invalid actor_id: %w", err)
+		}
+		params.ActorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	for name, field := range map[string]*sql.NullString{
+		"action":      &params.Action,
+		"target_type": &params.TargetType,
+		"target_id":   &params.TargetID,
+		"request_id":  &params.RequestID,
+	} {
+		if s := query.Get(name); s != "" {
+			*field = sql.NullString{String: s, Valid: true}
+		}
+	}
+
+	for name, field := range map[string]*sql.NullTime{
+		"since": &params.Since,
+		"until": &params.Until,
+	} {
+		if s := query.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return params, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*field = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+	}
+
+	if s := query.Get("before"); s != "" {
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return params, fmt.Errorf("invalid before: %w", err)
+		}
+		params.Before = sql.NullInt64{Int64: before, Valid: true}
+	}
+
+	return params, nil
+}
+
+// handlerAdminAuditList returns audit events newest first, filtered by any
+// of actor_id, action, target_type, target_id, request_id, since and until.
+func (cfg *apiConfig) handlerAdminAuditList(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Events     []AuditEvent `json:"events"`
+		NextBefore *int64       `json:"next_before,omitempty"`
+	}
+
+	params, err := auditEventsParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbEvents, err := cfg.db.GetAuditEvents(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get audit events", err)
+		return
+	}
+
+	resp := response{Events: []AuditEvent{}}
+	for _, e := range dbEvents {
+		resp.Events = append(resp.Events, auditEventResponse(e))
+	}
+	if len(dbEvents) == int(params.MaxResults) {
+		resp.NextBefore = &dbEvents[len(dbEvents)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerAdminAuditVerify walks the whole audit log checking the hash
+// chain, and reports the first entry that has been tampered with.
+func (cfg *apiConfig) handlerAdminAuditVerify(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Valid          bool   `json:"valid"`
+		Checked        int    `json:"checked"`
+		FirstInvalidID *int64 `json:"first_invalid_id,omitempty"`
+		Error          string `json:"error,omitempty"`
+	}
+
+	resp := response{Valid: true}
+	prevSeq := int64(0)
+	prevHash := audit.GenesisHash
+	for {
+		dbEvents, err := cfg.db.GetAuditEventsAfter(r.Context(), database.GetAuditEventsAfterParams{
+			ID:    prevSeq,
+			Limit: auditVerifyBatchSize,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get audit events", err)
+			return
+		}
+		if len(dbEvents) == 0 {
+			break
+		}
+
+		entries := make([]audit.Entry, 0, len(dbEvents))
+		for _, e := range dbEvents {
+			entries = append(entries, auditEntry(e))
+		}
+
+		invalidID, err := audit.Verify(entries, prevSeq, prevHash)
+		if err != nil {
+			resp.Valid = false
+			resp.FirstInvalidID = &invalidID
+			resp.Error = err.Error()
+			for i, e := range entries {
+				if e.Seq == invalidID {
+					resp.Checked += i
+					break
+				}
+			}
+			break
+		}
+
+		resp.Checked += len(entries)
+		last := entries[len(entries)-1]
+		prevSeq = last.Seq
+		prevHash = last.Hash
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
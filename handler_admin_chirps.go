@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// handlerAdminChirpsGet returns a chirp whether or not its author has
+// deleted it, so moderators can still see what was posted.
+func (cfg *apiConfig) handlerAdminChirpsGet(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirpByIDWithDeleted(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	chirp, err := cfg.chirpResponse(r.Context(), dbChirp, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) handlerAdminChirpsDeleted(w http.ResponseWriter, r *http.Request) {
+	dbChirps, err := cfg.db.GetDeletedChirps(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get deleted chirps", err)
+		return
+	}
+
+	chirps, err := cfg.chirpsResponse(r.Context(), dbChirps, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+// handlerAdminChirpsDelete deletes any user's chirp, the same way its
+// author could.
+func (cfg *apiConfig) handlerAdminChirpsDelete(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = deleteChirp(r.Context(), qtx, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    requestAccessToken(r).UserID,
+		Action:     auditChirpDeleted,
+		TargetType: auditTargetChirp,
+		TargetID:   chirp.ID,
+		Metadata:   map[string]any{"author_id": chirp.UserID},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
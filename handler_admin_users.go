@@ -0,0 +1,494 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultUserSearchLimit = 20
+	maxUserSearchLimit     = 100
+)
+
+// AdminUser is a user as moderators see them, including their role and any
+// restrictions on their account.
+type AdminUser struct {
+	User
+	Role             string     `json:"role"`
+	SuspendedUntil   *time.Time `json:"suspended_until"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	BannedAt         *time.Time `json:"banned_at"`
+	BanReason        string     `json:"ban_reason,omitempty"`
+}
+
+func adminUserResponse(u database.User) AdminUser {
+	user := AdminUser{
+		User: User{
+			ID:          u.ID,
+			CreatedAt:   u.CreatedAt,
+			UpdatedAt:   u.UpdatedAt,
+			Email:       u.Email,
+			IsChirpyRed: u.IsChirpyRed,
+			Username:    u.Username.String,
+			Protected:   u.Protected,
+		},
+		Role:             u.Role,
+		SuspensionReason: u.SuspensionReason.String,
+		BanReason:        u.BanReason.String,
+	}
+	if u.SuspendedUntil.Valid {
+		user.SuspendedUntil = &u.SuspendedUntil.Time
+	}
+	if u.BannedAt.Valid {
+		user.BannedAt = &u.BannedAt.Time
+	}
+	return user
+}
+
+// handlerAdminUsersSearch finds users whose email or username contains q.
+func (cfg *apiConfig) handlerAdminUsersSearch(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r, defaultUserSearchLimit, maxUserSearchLimit)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbUsers, err := cfg.db.SearchUsers(r.Context(), database.SearchUsersParams{
+		Query:      r.URL.Query().Get("q"),
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't search users", err)
+		return
+	}
+
+	users := []AdminUser{}
+	for _, u := range dbUsers {
+		users = append(users, adminUserResponse(u))
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// adminTargetUser loads the user named in the path, responding with an
+// error if they don't exist or the caller may not act on them.
+func (cfg *apiConfig) adminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return database.User{}, false
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return database.User{}, false
+	}
+
+	err = checkAdminTarget(requestAccessToken(r), user)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// checkAdminTarget returns why actor may not act on user, or nil if they
+// may. Nobody can act on themselves, and only admins can act on moderators
+// and admins.
+func checkAdminTarget(actor auth.AccessToken, user database.User) error {
+	if user.ID == actor.UserID {
+		return errors.New("you can't do this to your own account")
+	}
+	if auth.Role(user.Role) != auth.RoleUser && !actor.HasRole(auth.RoleAdmin) {
+		return errors.New("only admins can act on moderators and admins")
+	}
+	return nil
+}
+
+// recordAdminAudit records an admin action on a user as part of the
+// transaction q is in, with whoever made the request as the actor.
+func recordAdminAudit(r *http.Request, q *database.Queries, action string, user database.User, metadata map[string]any) error {
+	return recordAudit(r, q, auditRecord{
+		ActorID:    requestAccessToken(r).UserID,
+		Action:     action,
+		TargetType: auditTargetUser,
+		TargetID:   user.ID,
+		Metadata:   metadata,
+	})
+}
+
+// handlerAdminUsersSuspend stops a user logging in until the given time
+// and ends their sessions. Access tokens they already hold keep working
+// until they expire.
+func (cfg *apiConfig) handlerAdminUsersSuspend(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Until  time.Time `json:"until"`
+		Reason string    `json:"reason"`
+	}
+
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if !params.Until.After(time.Now()) {
+		err := errors.New("until must be in the future")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+		ID:               user.ID,
+		SuspendedUntil:   sql.NullTime{Time: params.Until.UTC(), Valid: true},
+		SuspensionReason: sql.NullString{String: params.Reason, Valid: params.Reason != ""},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+		return
+	}
+
+	err = qtx.RevokeAllRefreshTokensForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditUserSuspended, user, map[string]any{
+		"until":  user.SuspendedUntil.Time,
+		"reason": params.Reason,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+func (cfg *apiConfig) handlerAdminUsersUnsuspend(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err = qtx.UnsuspendUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unsuspending user", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditUserUnsuspended, user, nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unsuspending user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// handlerAdminUsersBan stops a user logging in until they're unbanned and
+// ends their sessions.
+func (cfg *apiConfig) handlerAdminUsersBan(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err = qtx.BanUser(r.Context(), database.BanUserParams{
+		ID:        user.ID,
+		BanReason: sql.NullString{String: params.Reason, Valid: params.Reason != ""},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error banning user", err)
+		return
+	}
+
+	err = qtx.RevokeAllRefreshTokensForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error banning user", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditUserBanned, user, map[string]any{"reason": params.Reason})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error banning user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+func (cfg *apiConfig) handlerAdminUsersUnban(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err = qtx.UnbanUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unbanning user", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditUserUnbanned, user, nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unbanning user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// handlerAdminUsersLogout revokes all of a user's refresh tokens, so they
+// have to log in again once their access token expires.
+func (cfg *apiConfig) handlerAdminUsersLogout(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = qtx.RevokeAllRefreshTokensForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditUserLoggedOut, user, nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerAdminUsersSetChirpyRed grants or revokes Chirpy Red outside of
+// Polka. A grant is announced like one from Polka.
+func (cfg *apiConfig) handlerAdminUsersSetChirpyRed(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IsChirpyRed bool `json:"is_chirpy_red"`
+	}
+
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	wasChirpyRed := user.IsChirpyRed
+	user, err = qtx.SetChirpyRed(r.Context(), database.SetChirpyRedParams{
+		ID:          user.ID,
+		IsChirpyRed: params.IsChirpyRed,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating Chirpy Red", err)
+		return
+	}
+
+	if user.IsChirpyRed && !wasChirpyRed {
+		err = enqueueEvent(r.Context(), qtx, eventUserUpgraded, userUpgradedEvent{UserID: user.ID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating Chirpy Red", err)
+			return
+		}
+	}
+
+	switch {
+	case user.IsChirpyRed && !wasChirpyRed:
+		err = recordAdminAudit(r, qtx, auditChirpyRedGranted, user, map[string]any{"source": "admin"})
+	case !user.IsChirpyRed && wasChirpyRed:
+		err = recordAdminAudit(r, qtx, auditChirpyRedRevoked, user, map[string]any{"source": "admin"})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating Chirpy Red", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// handlerAdminUsersSetRole changes a user's role. It takes effect on their
+// next request; the roles in access tokens they already hold are out of
+// date until they refresh, but nothing relies on them.
+func (cfg *apiConfig) handlerAdminUsersSetRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	oldRole := user.Role
+	user, err = qtx.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: string(role),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating role", err)
+		return
+	}
+
+	err = recordAdminAudit(r, qtx, auditRoleChanged, user, map[string]any{
+		"old_role": oldRole,
+		"new_role": user.Role,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating role", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, adminUserResponse(user))
+}
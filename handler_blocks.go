@@ -0,0 +1,272 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RelatedUser is an entry in the lists of users someone has blocked or
+// muted.
+type RelatedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// relationUsers reads the authenticated user and the user named in the
+// path for the block and mute endpoints, responding with an error if
+// either is missing or they're the same user.
+func (cfg *apiConfig) relationUsers(w http.ResponseWriter, r *http.Request) (userID, otherID uuid.UUID, ok bool) {
+	otherID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, err = auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	if otherID == userID {
+		err := errors.New("you can't do this to yourself")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, otherID, true
+}
+
+// handlerUsersBlock blocks a user and removes any follows and follow
+// requests between the two of them. Blocking someone already blocked hands
+// back the existing block.
+func (cfg *apiConfig) handlerUsersBlock(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.db.GetUserByID(r.Context(), blockedID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	status := http.StatusCreated
+	block, err := qtx.CreateBlock(r.Context(), database.CreateBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusOK
+		block, err = qtx.GetBlock(r.Context(), database.GetBlockParams{
+			BlockerID: userID,
+			BlockedID: blockedID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error blocking user", err)
+		return
+	}
+
+	for _, follow := range []database.DeleteFollowParams{
+		{FollowerID: userID, FolloweeID: blockedID},
+		{FollowerID: blockedID, FolloweeID: userID},
+	} {
+		err = qtx.DeleteFollow(r.Context(), follow)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error blocking user", err)
+			return
+		}
+	}
+	for _, request := range []database.DeleteFollowRequestParams{
+		{RequesterID: userID, TargetID: blockedID},
+		{RequesterID: blockedID, TargetID: userID},
+	} {
+		_, err = qtx.DeleteFollowRequest(r.Context(), request)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error blocking user", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error blocking user", err)
+		return
+	}
+
+	respondWithJSON(w, status, Block{
+		BlockerID: block.BlockerID,
+		BlockedID: block.BlockedID,
+		CreatedAt: block.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerUsersUnblock(w http.ResponseWriter, r *http.Request) {
+	userID, blockedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: userID,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unblocking user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerBlocksList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	rows, err := cfg.db.GetBlockedUsers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get blocked users", err)
+		return
+	}
+
+	users := []RelatedUser{}
+	for _, row := range rows {
+		users = append(users, RelatedUser{
+			UserID:    row.UserID,
+			Username:  row.Username.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// handlerUsersMute hides a user's chirps from the caller without them
+// knowing. Muting someone already muted hands back the existing mute.
+func (cfg *apiConfig) handlerUsersMute(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.db.GetUserByID(r.Context(), mutedID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	status := http.StatusCreated
+	mute, err := cfg.db.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusOK
+		mute, err = cfg.db.GetMute(r.Context(), database.GetMuteParams{
+			MuterID: userID,
+			MutedID: mutedID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error muting user", err)
+		return
+	}
+
+	respondWithJSON(w, status, Mute{
+		MuterID:   mute.MuterID,
+		MutedID:   mute.MutedID,
+		CreatedAt: mute.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerUsersUnmute(w http.ResponseWriter, r *http.Request) {
+	userID, mutedID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: userID,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unmuting user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerMutesList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	rows, err := cfg.db.GetMutedUsers(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get muted users", err)
+		return
+	}
+
+	users := []RelatedUser{}
+	for _, row := range rows {
+		users = append(users, RelatedUser{
+			UserID:    row.UserID,
+			Username:  row.Username.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
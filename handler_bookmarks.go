@@ -0,0 +1,180 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultBookmarksLimit = 20
+	maxBookmarksLimit     = 100
+)
+
+type Bookmark struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// handlerChirpsBookmark saves a chirp to the authenticated user's
+// bookmarks, which only they can see. Bookmarking a rechirp saves its
+// original, and bookmarking a chirp twice hands back the first bookmark.
+func (cfg *apiConfig) handlerChirpsBookmark(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	chirpID = originalChirpID(target)
+
+	_, err = getViewableChirp(r.Context(), cfg.db, chirpID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	status := http.StatusCreated
+	bookmark, err := cfg.db.CreateBookmark(r.Context(), database.CreateBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusOK
+		bookmark, err = cfg.db.GetBookmark(r.Context(), database.GetBookmarkParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error bookmarking chirp", err)
+		return
+	}
+
+	respondWithJSON(w, status, Bookmark{
+		ChirpID:   bookmark.ChirpID,
+		CreatedAt: bookmark.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerChirpsUnbookmark(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	// A rechirp was bookmarked as its original. If the chirp is gone there
+	// is nothing left to unbookmark.
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err == nil {
+		chirpID = originalChirpID(target)
+	}
+
+	err = cfg.db.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error removing bookmark", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerBookmarksList returns the user's bookmarked chirps, most recently
+// bookmarked first. Pages are fetched by passing the next_before of the
+// previous page as before. Chirps the user can no longer see are left
+// out, so a page can come back short.
+func (cfg *apiConfig) handlerBookmarksList(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Chirps     []Chirp    `json:"chirps"`
+		NextBefore *uuid.UUID `json:"next_before,omitempty"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	limit, err := queryLimit(r, defaultBookmarksLimit, maxBookmarksLimit)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	before := uuid.NullUUID{}
+	if s := r.URL.Query().Get("before"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid before ID", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbChirps, err := cfg.db.GetBookmarkedChirps(r.Context(), database.GetBookmarkedChirpsParams{
+		UserID:     userID,
+		Before:     before,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get bookmarks", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	resp := response{Chirps: chirps}
+	if len(dbChirps) == limit {
+		resp.NextBefore = &dbChirps[len(dbChirps)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
 	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/chonginator/chirpy/internal/moderation"
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 )
 
 type Chirp struct {
@@ -18,12 +25,39 @@ type Chirp struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Body string 				`json:"body"`
 	UserID uuid.UUID 		`json:"user_id"`
+	Edited    bool       `json:"edited"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	HiddenAt  *time.Time `json:"hidden_at,omitempty"`
+	Entities  []Entity   `json:"entities"`
+	Media     []Media    `json:"media"`
+	Poll      *Poll      `json:"poll,omitempty"`
+	LikeCount int32      `json:"like_count"`
+	LikedByMe *bool      `json:"liked_by_me,omitempty"`
+	Pinned    bool       `json:"pinned,omitempty"`
+
+	RechirpOf     *ChirpEmbed `json:"rechirp_of,omitempty"`
+	QuotedChirpID *uuid.UUID  `json:"quoted_chirp_id,omitempty"`
+	QuotedChirp   *ChirpEmbed `json:"quoted_chirp,omitempty"`
+	ReplyToID     *uuid.UUID  `json:"reply_to_id,omitempty"`
+	RechirpCount  int32       `json:"rechirp_count"`
+	QuoteCount    int32       `json:"quote_count"`
+	RechirpedByMe *bool       `json:"rechirped_by_me,omitempty"`
+
+	Visibility feed.Visibility `json:"visibility"`
+
+	// Filtered is set when the chirp matches the viewer's muted words.
+	Filtered *ChirpFilter `json:"filtered,omitempty"`
 }
 
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string 		 `json:"body"`
-		UserID uuid.UUID `json:"user_id"`
+		Body          string            `json:"body"`
+		UserID        uuid.UUID         `json:"user_id"`
+		QuotedChirpID *uuid.UUID        `json:"quoted_chirp_id"`
+		ReplyToID     *uuid.UUID        `json:"reply_to_id"`
+		Media         []MediaAttachment `json:"media"`
+		Visibility    feed.Visibility   `json:"visibility"`
+		Poll          *NewPoll          `json:"poll"`
 	}
 
 	token, err := auth.GetBearerToken(r.Header)
@@ -46,60 +80,322 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if params.Body == "" {
-		err := fmt.Errorf("body field is empty")
-		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
 		return
 	}
 
-	err = validateChirp(params.Body)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
 
-	cleanedChirp := cleanChirp(params.Body)
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body: cleanedChirp,
-		UserID: userID,
+	chirp, err := cfg.createChirp(r.Context(), qtx, user, newChirp{
+		Body:          params.Body,
+		QuotedChirpID: params.QuotedChirpID,
+		ReplyToID:     params.ReplyToID,
+		Media:         params.Media,
+		Visibility:    params.Visibility,
+		Poll:          params.Poll,
 	})
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-			ID: chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body: chirp.Body,
-			UserID: chirp.UserID,
-		},
-	)
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
 }
 
-func validateChirp(chirp string) error {
-	const maxChirpLength = 140
-	if len(chirp) > maxChirpLength {
-		err := fmt.Errorf("chirp exceeds %d characters", maxChirpLength)
-		return err
+var (
+	errChirpEmpty          = errors.New("body field is empty")
+	errChirpRejected       = errors.New("chirp contains disallowed content")
+	errQuotedChirpNotFound = errors.New("couldn't find quoted chirp")
+	errReplyChirpNotFound  = errors.New("couldn't find chirp being replied to")
+	errInvalidVisibility   = fmt.Errorf("visibility must be %s, %s or %s", feed.Public, feed.Followers, feed.Mentioned)
+)
+
+// newChirp is what an author supplies when creating a chirp.
+type newChirp struct {
+	Body          string
+	QuotedChirpID *uuid.UUID
+	ReplyToID     *uuid.UUID
+	Media         []MediaAttachment
+	// Visibility defaults to public.
+	Visibility feed.Visibility
+	Poll       *NewPoll
+}
+
+// createChirp checks and stores a chirp for author, along with everything
+// that hangs off it: share counts, moderation flags, entities and media,
+// and announces it on the chirp stream and to webhooks. q should be bound
+// to a transaction so a failure part way leaves nothing behind, and the
+// announcements only go out if it commits. Errors from the checks are
+// returned as is for respondWithChirpError.
+func (cfg *apiConfig) createChirp(ctx context.Context, q *database.Queries, author database.User, params newChirp) (database.Chirp, error) {
+	// Access tokens outlive a ban or suspension, so it's checked here
+	// rather than trusted from login.
+	err := accountRestriction(author)
+	if err != nil {
+		return database.Chirp{}, err
 	}
-	return nil
+
+	if params.Body == "" {
+		return database.Chirp{}, errChirpEmpty
+	}
+
+	if params.Visibility == "" {
+		params.Visibility = feed.Public
+	}
+	if !params.Visibility.Valid() {
+		return database.Chirp{}, errInvalidVisibility
+	}
+
+	err = validateAttachments(params.Media)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	var pollOptions []string
+	var flags []moderation.Match
+	if params.Poll != nil {
+		if len(params.Media) > 0 {
+			return database.Chirp{}, errPollWithMedia
+		}
+		pollOptions, flags, err = cfg.checkPoll(*params.Poll, time.Now())
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	moderated, err := cfg.checkChirp(params.Body, author.IsChirpyRed)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	quoted, err := resolveChirpReference(ctx, q, author.ID, params.QuotedChirpID, errQuotedChirpNotFound)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	replyTo, err := resolveChirpReference(ctx, q, author.ID, params.ReplyToID, errReplyChirpNotFound)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:          moderated.Text,
+		UserID:        author.ID,
+		QuotedChirpID: quoted,
+		ReplyToID:     replyTo,
+		Visibility:    string(params.Visibility),
+	})
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("couldn't create chirp: %w", err)
+	}
+
+	err = updateShareCounts(ctx, q, chirp, 1)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("couldn't update quote count: %w", err)
+	}
+
+	if moderated.Flagged() {
+		flags = append(moderated.Matches, flags...)
+	}
+	if len(flags) > 0 {
+		err = flagChirp(ctx, q, chirp.ID, moderation.Result{Matches: flags}.Reasons())
+		if err != nil {
+			return database.Chirp{}, fmt.Errorf("couldn't flag chirp: %w", err)
+		}
+	}
+
+	err = saveChirpEntities(ctx, q, chirp)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("couldn't save chirp entities: %w", err)
+	}
+
+	err = attachChirpMedia(ctx, q, chirp, params.Media)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if params.Poll != nil {
+		err = createPoll(ctx, q, chirp, pollOptions, params.Poll.ClosesAt)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	err = announceChirpCreated(ctx, q, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	return chirp, nil
+}
+
+// resolveChirpReference looks up the chirp a new chirp by authorID quotes
+// or replies to. A rechirp stands in for its original, and a chirp the
+// author can't see, such as one by a user a block stands between them and,
+// is treated as missing.
+func resolveChirpReference(ctx context.Context, q *database.Queries, authorID uuid.UUID, chirpID *uuid.UUID, errNotFound error) (uuid.NullUUID, error) {
+	if chirpID == nil {
+		return uuid.NullUUID{}, nil
+	}
+	chirp, err := q.GetChirpByID(ctx, *chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, errNotFound
+	}
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	if chirp.RechirpOfID.Valid {
+		chirp, err = q.GetChirpByID(ctx, chirp.RechirpOfID.UUID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.NullUUID{}, errNotFound
+		}
+		if err != nil {
+			return uuid.NullUUID{}, err
+		}
+	}
+
+	viewable, err := canViewChirp(ctx, q, chirp, authorID)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	if !viewable {
+		return uuid.NullUUID{}, errNotFound
+	}
+	return uuid.NullUUID{UUID: chirp.ID, Valid: true}, nil
+}
+
+// isChirpCheckError reports whether err came from checking a chirp's
+// content rather than from storing it, so retrying won't help.
+func isChirpCheckError(err error) bool {
+	var lengthErr *chirpLengthError
+	return errors.As(err, &lengthErr) ||
+		errors.Is(err, errChirpEmpty) ||
+		errors.Is(err, errChirpRejected) ||
+		errors.Is(err, errQuotedChirpNotFound) ||
+		errors.Is(err, errReplyChirpNotFound) ||
+		errors.Is(err, errInvalidVisibility) ||
+		errors.Is(err, errInvalidPoll) ||
+		errors.Is(err, errPollWithMedia) ||
+		errors.Is(err, errTooManyMedia) ||
+		errors.Is(err, errAltTextTooLong) ||
+		errors.Is(err, errMediaNotFound) ||
+		errors.Is(err, errDraftAttachments)
 }
 
-func cleanChirp(chirp string) string {
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert": {},
-		"fornax": {},
+// checkChirp runs a chirp body through every check a chirp must pass before
+// it's stored, whether it's new or an edit: NFC normalization, the
+// author's length limit and the moderation pipeline. The returned result
+// holds the text to store.
+func (cfg *apiConfig) checkChirp(body string, isChirpyRed bool) (moderation.Result, error) {
+	body = norm.NFC.String(body)
+	err := validateChirp(body, chirpLengthLimit(isChirpyRed))
+	if err != nil {
+		return moderation.Result{}, err
 	}
 
-	words := strings.Split(chirp, " ")
-	for i, char := range(words) {
-		if _, ok := badWords[strings.ToLower(char)]; ok {
-			words[i] = "****"
+	moderated := cfg.cleanChirp(body)
+	if moderated.Rejected() {
+		return moderation.Result{}, errChirpRejected
+	}
+	return moderated, nil
+}
+
+const (
+	maxChirpLength          = 140
+	maxChirpyRedChirpLength = 280
+)
+
+func chirpLengthLimit(isChirpyRed bool) int {
+	if isChirpyRed {
+		return maxChirpyRedChirpLength
+	}
+	return maxChirpLength
+}
+
+type chirpLengthError struct {
+	Length int
+	Limit  int
+}
+
+func (e *chirpLengthError) Error() string {
+	return fmt.Sprintf("chirp exceeds %d characters", e.Limit)
+}
+
+// validateChirp checks a chirp's length in user-perceived characters
+// against the author's plan limit.
+func validateChirp(chirp string, limit int) error {
+	length := chirptext.Length(chirp)
+	if length > limit {
+		return &chirpLengthError{
+			Length: length,
+			Limit:  limit,
 		}
 	}
+	return nil
+}
+
+// respondWithChirpError reports a checkChirp or createChirp failure. Length
+// errors include the computed length and the remaining budget, which is
+// negative by how far the chirp is over.
+func respondWithChirpError(w http.ResponseWriter, err error) {
+	type response struct {
+		Error     string
+		Length    int `json:"length"`
+		Limit     int `json:"limit"`
+		Remaining int `json:"remaining"`
+	}
 
-	return strings.Join(words, " ")
-}
\ No newline at end of file
+	var lengthErr *chirpLengthError
+	switch {
+	case errors.As(err, &lengthErr):
+	case isAccountRestriction(err):
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	case errors.Is(err, errQuotedChirpNotFound):
+		respondWithError(w, http.StatusNotFound, "Couldn't find quoted chirp", err)
+		return
+	case errors.Is(err, errReplyChirpNotFound):
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp being replied to", err)
+		return
+	case isChirpCheckError(err):
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp", err)
+		return
+	}
+
+	log.Println(err)
+	respondWithJSON(w, http.StatusBadRequest, response{
+		Error:     lengthErr.Error(),
+		Length:    lengthErr.Length,
+		Limit:     lengthErr.Limit,
+		Remaining: lengthErr.Limit - lengthErr.Length,
+	})
+}
+
+// cleanChirp runs a chirp through the moderation pipeline. The returned
+// text has masked words replaced; callers must check whether the result was
+// rejected or flagged for review.
+func (cfg *apiConfig) cleanChirp(chirp string) moderation.Result {
+	return cfg.moderator.Load().Moderate(chirp)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -40,11 +44,93 @@ func (cfg *apiConfig) handlerChirpsDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = cfg.db.DeleteChirp(r.Context(), chirpID)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Error deleting chirp", err)
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = deleteChirp(r.Context(), qtx, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    userID,
+		Action:     auditChirpDeleted,
+		TargetType: auditTargetChirp,
+		TargetID:   chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting chirp", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// deleteChirp deletes a chirp as part of the transaction q is in. A rechirp
+// has no content of its own to recover, so it's removed outright;
+// everything else goes to the author's trash.
+func deleteChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	public, err := isPublicChirp(ctx, q, chirp)
+	if err != nil {
+		return err
+	}
+
+	if chirp.RechirpOfID.Valid {
+		err = q.DeleteChirp(ctx, chirp.ID)
+	} else {
+		err = q.SoftDeleteChirp(ctx, chirp.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = updateShareCounts(ctx, q, chirp, -1)
+	if err != nil {
+		return err
+	}
+
+	// A restored chirp comes back unpinned.
+	err = q.UnpinChirp(ctx, database.UnpinChirpParams{
+		UserID:  chirp.UserID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return announceChirpDeleted(ctx, q, chirp, public)
+}
+
+// purgeDeletedChirps hard-deletes chirps that have been in the trash for
+// longer than chirpTrashRetention.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.db.PurgeDeletedChirps(ctx, time.Now().Add(-chirpTrashRetention).UTC())
+		if err != nil {
+			log.Printf("Error purging deleted chirps: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted chirps", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"slices"
 
+	"github.com/chonginator/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -34,31 +35,44 @@ func (cfg *apiConfig) handlerChirpsRetrieve(w http.ResponseWriter, r *http.Reque
 		sortBy = sortAscending
 	}
 
-	chirpsResponse := []Chirp{}
+	filteredChirps := []database.Chirp{}
 	for _, chirp := range dbChirps {
 		if authorID != uuid.Nil && chirp.UserID != authorID {
 			continue
 		}
+		filteredChirps = append(filteredChirps, chirp)
+	}
 
-		chirpsResponse = append(chirpsResponse, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+	chirpsResponse, err := cfg.visibleChirpsResponse(r.Context(), filteredChirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
 	}
 
-	slices.SortFunc(chirpsResponse, func(chirpA, chirpB Chirp) int {
-		if sortBy == sortAscending {
-			return chirpA.CreatedAt.Compare(chirpB.CreatedAt)
+	sortChirps(chirpsResponse, sortBy)
+
+	// An author's pinned chirps lead their timeline.
+	if authorID != uuid.Nil {
+		pinned, err := pinnedChirps(r.Context(), cfg.db, authorID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get pinned chirps", err)
+			return
 		}
-		return -chirpA.CreatedAt.Compare(chirpB.CreatedAt)
-	})
+		sortPinnedFirst(chirpsResponse, pinned)
+	}
 
 	respondWithJSON(w, http.StatusOK, chirpsResponse)
 }
 
+func sortChirps(chirps []Chirp, sortBy string) {
+	slices.SortFunc(chirps, func(chirpA, chirpB Chirp) int {
+		if sortBy == sortDescending {
+			return -chirpA.CreatedAt.Compare(chirpB.CreatedAt)
+		}
+		return chirpA.CreatedAt.Compare(chirpB.CreatedAt)
+	})
+}
+
 func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 	chirpIDString := r.PathValue("chirpID")
 	chirpUUID, err := uuid.Parse(chirpIDString)
@@ -67,17 +81,58 @@ func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpUUID)
+	// Chirps the viewer can't see look the same as ones that don't exist.
+	viewerID := cfg.viewerID(r)
+	dbChirp, err := getViewableChirp(r.Context(), cfg.db, chirpUUID, viewerID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Error couldn't get chirp", err)
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
-	})
+	chirp, err := cfg.chirpResponse(r.Context(), dbChirp, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	// A chirp opened directly is shown even if it matches a muted word,
+	// but still marked so the client can collapse it.
+	wordFilter, err := userWordFilter(r.Context(), cfg.db, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get muted words", err)
+		return
+	}
+	chirp.Filtered = matchMutedWords(wordFilter, chirp)
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+// handlerChirpsReplies lists the direct replies to a chirp, oldest first.
+func (cfg *apiConfig) handlerChirpsReplies(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+	_, err = getViewableChirp(r.Context(), cfg.db, chirpID, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	dbChirps, err := cfg.db.GetChirpReplies(r.Context(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get replies", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, viewerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpTrashRetention is how long a deleted chirp can be restored before
+// purgeDeletedChirps removes it for good.
+const chirpTrashRetention = 30 * 24 * time.Hour
+
+func (cfg *apiConfig) handlerChirpsTrash(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	dbChirps, err := cfg.db.GetDeletedChirpsByUserID(r.Context(), database.GetDeletedChirpsByUserIDParams{
+		UserID:       userID,
+		DeletedAfter: time.Now().Add(-chirpTrashRetention).UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get deleted chirps", err)
+		return
+	}
+
+	chirps, err := cfg.chirpsResponse(r.Context(), dbChirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+func (cfg *apiConfig) handlerChirpsRestore(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	dbChirp, err := qtx.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:           chirpID,
+		UserID:       userID,
+		DeletedAfter: time.Now().Add(-chirpTrashRetention).UTC(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp in trash", err)
+		return
+	}
+
+	err = updateShareCounts(r.Context(), qtx, dbChirp, 1)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating share counts", err)
+		return
+	}
+
+	// Deleting it was announced, so restoring it is too, unless moderators
+	// have since hidden it.
+	if !dbChirp.HiddenAt.Valid {
+		err = announceChirpCreated(r.Context(), qtx, dbChirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring chirp", err)
+		return
+	}
+
+	chirp, err := cfg.chirpResponse(r.Context(), dbChirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerChirpsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if params.Body == "" {
+		err := fmt.Errorf("body field is empty")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	moderated, err := cfg.checkChirp(params.Body, user.IsChirpyRed)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Locking the row keeps two concurrent edits from both saving the same
+	// body as the previous revision.
+	chirp, err := qtx.GetChirpByIDForUpdate(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		err := errors.New("unauthorized action")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	if chirp.RechirpOfID.Valid {
+		err := errors.New("rechirps can't be edited")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if time.Since(chirp.CreatedAt) > cfg.chirpEditWindow {
+		err := fmt.Errorf("chirps can only be edited within %s of posting", cfg.chirpEditWindow)
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	if moderated.Text != chirp.Body {
+		err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID:   chirp.ID,
+			Body:      chirp.Body,
+			CreatedAt: chirp.UpdatedAt,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving chirp revision", err)
+			return
+		}
+
+		chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:   chirp.ID,
+			Body: moderated.Text,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating chirp", err)
+			return
+		}
+
+		if moderated.Flagged() {
+			err = flagChirp(r.Context(), qtx, chirp.ID, moderated.Reasons())
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error flagging chirp", err)
+				return
+			}
+		}
+
+		err = replaceChirpEntities(r.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving chirp entities", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating chirp", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// replaceChirpEntities re-parses an edited chirp's body, dropping the
+// hashtags and mentions its previous version had.
+func replaceChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	return saveChirpEntities(ctx, q, chirp)
+}
+
+func (cfg *apiConfig) handlerChirpsHistory(w http.ResponseWriter, r *http.Request) {
+	type revision struct {
+		Body       string     `json:"body"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ReplacedAt *time.Time `json:"replaced_at"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	chirp, err := getViewableChirp(r.Context(), cfg.db, chirpID, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	dbRevisions, err := cfg.db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp history", err)
+		return
+	}
+
+	// Oldest first, ending with the current version, which hasn't been
+	// replaced.
+	revisions := []revision{}
+	for _, rev := range dbRevisions {
+		revisions = append(revisions, revision{
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: &rev.ReplacedAt,
+		})
+	}
+	revisions = append(revisions, revision{
+		Body:      chirp.Body,
+		CreatedAt: chirp.UpdatedAt,
+	})
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/google/uuid"
+)
+
+type Draft struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Body          string          `json:"body"`
+	QuotedChirpID *uuid.UUID      `json:"quoted_chirp_id"`
+	Visibility    feed.Visibility `json:"visibility"`
+	PublishAt     *time.Time      `json:"publish_at"`
+	PublishError  string          `json:"publish_error,omitempty"`
+}
+
+func draftResponse(d database.ChirpDraft) Draft {
+	draft := Draft{
+		ID:           d.ID,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
+		UserID:       d.UserID,
+		Body:         d.Body,
+		Visibility:   feed.Visibility(d.Visibility),
+		PublishError: d.PublishError.String,
+	}
+	if d.QuotedChirpID.Valid {
+		draft.QuotedChirpID = &d.QuotedChirpID.UUID
+	}
+	if d.PublishAt.Valid {
+		draft.PublishAt = &d.PublishAt.Time
+	}
+	return draft
+}
+
+// errDraftAttachments is returned for a draft with media or a poll, which
+// drafts can't hold.
+var errDraftAttachments = errors.New("drafts can't have media or a poll")
+
+// draftParameters is the body accepted when saving a draft. Leaving
+// publish_at out keeps the draft unscheduled. Media and Poll are only read
+// so that a draft with them is refused rather than published without them.
+type draftParameters struct {
+	Body          string            `json:"body"`
+	QuotedChirpID *uuid.UUID        `json:"quoted_chirp_id"`
+	Visibility    feed.Visibility   `json:"visibility"`
+	PublishAt     *time.Time        `json:"publish_at"`
+	Media         []MediaAttachment `json:"media"`
+	Poll          *NewPoll          `json:"poll"`
+}
+
+// checkDraft validates a draft before it's saved. Unscheduled drafts can
+// hold any body; scheduled ones must pass the same checks as a chirp, so
+// problems surface now rather than at publish time.
+func (cfg *apiConfig) checkDraft(params draftParameters, author database.User) (sql.NullTime, error) {
+	if len(params.Media) > 0 || params.Poll != nil {
+		return sql.NullTime{}, errDraftAttachments
+	}
+	if !params.Visibility.Valid() {
+		return sql.NullTime{}, errInvalidVisibility
+	}
+	if params.PublishAt == nil {
+		return sql.NullTime{}, nil
+	}
+	if params.Body == "" {
+		return sql.NullTime{}, errChirpEmpty
+	}
+	_, err := cfg.checkChirp(params.Body, author.IsChirpyRed)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}, nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+func (cfg *apiConfig) handlerDraftsCreate(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := draftParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if params.PublishAt != nil && !params.PublishAt.After(time.Now()) {
+		err := errors.New("publish_at must be in the future")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	if params.Visibility == "" {
+		params.Visibility = feed.Public
+	}
+	publishAt, err := cfg.checkDraft(params, user)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	draft, err := cfg.db.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:        userID,
+		Body:          params.Body,
+		QuotedChirpID: nullUUID(params.QuotedChirpID),
+		PublishAt:     publishAt,
+		Visibility:    string(params.Visibility),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, draftResponse(draft))
+}
+
+func (cfg *apiConfig) handlerDraftsList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	dbDrafts, err := cfg.db.GetDraftsByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get drafts", err)
+		return
+	}
+
+	drafts := []Draft{}
+	for _, d := range dbDrafts {
+		drafts = append(drafts, draftResponse(d))
+	}
+
+	respondWithJSON(w, http.StatusOK, drafts)
+}
+
+func (cfg *apiConfig) handlerDraftsUpdate(w http.ResponseWriter, r *http.Request) {
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := draftParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if params.PublishAt != nil && !params.PublishAt.After(time.Now()) {
+		err := errors.New("publish_at must be in the future")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	if params.Visibility == "" {
+		params.Visibility = feed.Public
+	}
+	publishAt, err := cfg.checkDraft(params, user)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Waiting on the row lock means an edit racing the scheduler either
+	// lands before the draft is published or finds it already gone.
+	_, err = qtx.GetDraftForUpdate(r.Context(), database.GetDraftForUpdateParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find draft", err)
+		return
+	}
+
+	draft, err := qtx.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:            draftID,
+		UserID:        userID,
+		Body:          params.Body,
+		QuotedChirpID: nullUUID(params.QuotedChirpID),
+		PublishAt:     publishAt,
+		Visibility:    string(params.Visibility),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating draft", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating draft", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, draftResponse(draft))
+}
+
+func (cfg *apiConfig) handlerDraftsDelete(w http.ResponseWriter, r *http.Request) {
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting draft", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find draft", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerDraftsPublish publishes a draft straight away, whether or not it's
+// scheduled.
+func (cfg *apiConfig) handlerDraftsPublish(w http.ResponseWriter, r *http.Request) {
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid draft ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	draft, err := qtx.GetDraftForUpdate(r.Context(), database.GetDraftForUpdateParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find draft", err)
+		return
+	}
+
+	chirp, err := cfg.publishDraft(r.Context(), qtx, user, draft)
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error publishing draft", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+// publishDraft turns a locked draft into a chirp and deletes the draft.
+func (cfg *apiConfig) publishDraft(ctx context.Context, q *database.Queries, author database.User, draft database.ChirpDraft) (database.Chirp, error) {
+	params := newChirp{
+		Body:       draft.Body,
+		Visibility: feed.Visibility(draft.Visibility),
+	}
+	if draft.QuotedChirpID.Valid {
+		params.QuotedChirpID = &draft.QuotedChirpID.UUID
+	}
+
+	chirp, err := cfg.createChirp(ctx, q, author, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	_, err = q.DeleteDraft(ctx, database.DeleteDraftParams{
+		ID:     draft.ID,
+		UserID: draft.UserID,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
+
+// scheduledChirpBatchSize caps how many due drafts one scheduler pass
+// publishes.
+const scheduledChirpBatchSize = 100
+
+// errScheduledPublishFailed is recorded on a scheduled draft that couldn't
+// be published for reasons other than the chirp checks.
+var errScheduledPublishFailed = errors.New("couldn't publish scheduled chirp")
+
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.publishDueDrafts(ctx)
+		if err != nil {
+			log.Printf("Error publishing scheduled chirps: %v", err)
+		} else if n > 0 {
+			log.Printf("Published %d scheduled chirps", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueDrafts publishes up to scheduledChirpBatchSize drafts whose
+// publish_at has passed, each in its own transaction so that one failing
+// draft can't hold back the rest. A draft that fails to publish is
+// unscheduled with the reason recorded for its author, rather than being
+// retried on every pass.
+func (cfg *apiConfig) publishDueDrafts(ctx context.Context) (int, error) {
+	published := 0
+	for range scheduledChirpBatchSize {
+		draftID, ok, err := cfg.publishNextDueDraft(ctx)
+		if draftID == uuid.Nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			return published, err
+		}
+		if err != nil {
+			log.Printf("Error publishing scheduled draft %s: %v", draftID, err)
+			err = cfg.db.SetDraftPublishError(ctx, database.SetDraftPublishErrorParams{
+				ID:           draftID,
+				PublishError: sql.NullString{String: errScheduledPublishFailed.Error(), Valid: true},
+			})
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+		if ok {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// publishNextDueDraft claims one due draft and publishes it, returning
+// the draft's ID and whether it was published. The draft is claimed with
+// FOR UPDATE SKIP LOCKED, so replicas running the scheduler at the same
+// time each take different drafts and none is published twice. A draft
+// that no longer passes the chirp checks, or whose author has since been
+// banned or suspended, is unscheduled with the reason recorded for them.
+func (cfg *apiConfig) publishNextDueDraft(ctx context.Context) (uuid.UUID, bool, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	draft, err := qtx.ClaimDueDraft(ctx)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	author, err := qtx.GetUserByID(ctx, draft.UserID)
+	if err != nil {
+		return draft.ID, false, err
+	}
+
+	published := true
+	_, err = cfg.publishDraft(ctx, qtx, author, draft)
+	if isChirpCheckError(err) || isAccountRestriction(err) {
+		published = false
+		err = qtx.SetDraftPublishError(ctx, database.SetDraftPublishErrorParams{
+			ID:           draft.ID,
+			PublishError: sql.NullString{String: err.Error(), Valid: true},
+		})
+	}
+	if err != nil {
+		return draft.ID, false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return draft.ID, false, err
+	}
+	return draft.ID, published, nil
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type FollowRequest struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	TargetID    uuid.UUID `json:"target_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// requestFollow asks the protected user targetID to let userID follow
+// them. Someone already following gets their follow back; asking again
+// hands back the pending request.
+func (cfg *apiConfig) requestFollow(w http.ResponseWriter, r *http.Request, userID, targetID uuid.UUID) {
+	follow, err := cfg.db.GetFollow(r.Context(), database.GetFollowParams{
+		FollowerID: userID,
+		FolloweeID: targetID,
+	})
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, Follow{
+			FollowerID: follow.FollowerID,
+			FolloweeID: follow.FolloweeID,
+			CreatedAt:  follow.CreatedAt,
+		})
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	request, err := cfg.db.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+		RequesterID: userID,
+		TargetID:    targetID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		request, err = cfg.db.GetFollowRequest(r.Context(), database.GetFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    targetID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error requesting to follow user", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, FollowRequest{
+		RequesterID: request.RequesterID,
+		TargetID:    request.TargetID,
+		CreatedAt:   request.CreatedAt,
+	})
+}
+
+// handlerFollowRequestsList lists the requests waiting for the
+// authenticated user's approval, oldest first.
+func (cfg *apiConfig) handlerFollowRequestsList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	rows, err := cfg.db.GetFollowRequests(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get follow requests", err)
+		return
+	}
+
+	users := []RelatedUser{}
+	for _, row := range rows {
+		users = append(users, RelatedUser{
+			UserID:    row.UserID,
+			Username:  row.Username.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// handlerFollowRequestsApprove turns a pending request from the user in
+// the path into a follow.
+func (cfg *apiConfig) handlerFollowRequestsApprove(w http.ResponseWriter, r *http.Request) {
+	userID, requesterID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	n, err := qtx.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find follow request", sql.ErrNoRows)
+		return
+	}
+
+	status := http.StatusCreated
+	follow, err := qtx.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: requesterID,
+		FolloweeID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusOK
+		follow, err = qtx.GetFollow(r.Context(), database.GetFollowParams{
+			FollowerID: requesterID,
+			FolloweeID: userID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+		return
+	}
+
+	if status == http.StatusCreated {
+		err = enqueueEvent(r.Context(), qtx, eventUserFollowed, userFollowedEvent{
+			FollowerID: requesterID,
+			FolloweeID: userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error approving follow request", err)
+		return
+	}
+
+	respondWithJSON(w, status, Follow{
+		FollowerID: follow.FollowerID,
+		FolloweeID: follow.FolloweeID,
+		CreatedAt:  follow.CreatedAt,
+	})
+}
+
+// handlerFollowRequestsDecline turns down a pending request. The requester
+// isn't told.
+func (cfg *apiConfig) handlerFollowRequestsDecline(w http.ResponseWriter, r *http.Request) {
+	userID, requesterID, ok := cfg.relationUsers(w, r)
+	if !ok {
+		return
+	}
+
+	n, err := cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error declining follow request", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find follow request", sql.ErrNoRows)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUsersPrivacy protects or unprotects the authenticated user's
+// account. Unprotecting it approves every pending follow request, since
+// nothing is left for them to wait for.
+func (cfg *apiConfig) handlerUsersPrivacy(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Protected bool `json:"protected"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.SetUserProtected(r.Context(), database.SetUserProtectedParams{
+		ID:        userID,
+		Protected: params.Protected,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update privacy", err)
+		return
+	}
+
+	if !user.Protected {
+		follows, err := qtx.ApproveAllFollowRequests(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't approve follow requests", err)
+			return
+		}
+		for _, follow := range follows {
+			err = enqueueEvent(r.Context(), qtx, eventUserFollowed, userFollowedEvent{
+				FollowerID: follow.FollowerID,
+				FolloweeID: follow.FolloweeID,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't approve follow requests", err)
+				return
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update privacy", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Email:       user.Email,
+		IsChirpyRed: user.IsChirpyRed,
+		Username:    user.Username.String,
+		Protected:   user.Protected,
+	})
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// handlerUsersFollow follows a user. Following a protected user instead
+// asks them to approve a follow request, answered with 202 Accepted.
+func (cfg *apiConfig) handlerUsersFollow(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	if followeeID == userID {
+		err := errors.New("you can't follow yourself")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	followee, err := cfg.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:  userID,
+		OtherID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+		return
+	}
+	if blocked {
+		err := errors.New("you can't follow this user")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	if followee.Protected {
+		cfg.requestFollow(w, r, userID, followeeID)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	status := http.StatusCreated
+	follow, err := qtx.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already following: hand back the existing follow.
+		status = http.StatusOK
+		follow, err = qtx.GetFollow(r.Context(), database.GetFollowParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	if status == http.StatusCreated {
+		err = enqueueEvent(r.Context(), qtx, eventUserFollowed, userFollowedEvent{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	respondWithJSON(w, status, Follow{
+		FollowerID: follow.FollowerID,
+		FolloweeID: follow.FolloweeID,
+		CreatedAt:  follow.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerUsersUnfollow(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	err = cfg.db.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user", err)
+		return
+	}
+
+	// Unfollowing also withdraws a request that's still pending.
+	_, err = cfg.db.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: userID,
+		TargetID:    followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerTimeline returns the chirps of everyone the user follows, along
+// with their own, newest first unless sort=asc.
+func (cfg *apiConfig) handlerTimeline(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != sortAscending && sortBy != sortDescending {
+		sortBy = sortDescending
+	}
+
+	dbChirps, err := cfg.db.GetTimelineChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get timeline", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	sortChirps(chirps, sortBy)
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/chonginator/chirpy/internal/chirptext"
+)
+
+func (cfg *apiConfig) handlerHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := chirptext.NormalizeTag(r.PathValue("tag"))
+
+	dbChirps, err := cfg.db.GetChirpsByHashtag(r.Context(), tag)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	sortChirps(chirps, r.URL.Query().Get("sort"))
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
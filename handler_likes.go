@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerChirpsLike(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, true)
+}
+
+func (cfg *apiConfig) handlerChirpsUnlike(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, false)
+}
+
+// setChirpLike likes or unlikes a chirp for the authenticated user. Both
+// directions are idempotent, so repeating a request leaves the count alone.
+func (cfg *apiConfig) setChirpLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	chirpID = originalChirpID(target)
+
+	_, err = getViewableChirp(r.Context(), cfg.db, chirpID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	if liked {
+		err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+	} else {
+		err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update like", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	chirp, err := cfg.chirpResponse(r.Context(), dbChirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) handlerChirpsLikesList(w http.ResponseWriter, r *http.Request) {
+	type like struct {
+		UserID    uuid.UUID `json:"user_id"`
+		Username  string    `json:"username,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	_, err = getViewableChirp(r.Context(), cfg.db, chirpID, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	dbLikes, err := cfg.db.GetChirpLikes(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get likes", err)
+		return
+	}
+
+	likes := []like{}
+	for _, l := range dbLikes {
+		likes = append(likes, like{
+			UserID:    l.UserID,
+			Username:  l.Username.String,
+			CreatedAt: l.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, likes)
+}
+
+func (cfg *apiConfig) handlerUsersLikesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	dbChirps, err := cfg.db.GetChirpsLikedByUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get liked chirps", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
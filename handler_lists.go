@@ -0,0 +1,428 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxListsPerUser          = 100
+	maxListMembers           = 500
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+)
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+}
+
+func listResponse(l database.List) List {
+	return List{
+		ID:          l.ID,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+		OwnerID:     l.OwnerID,
+		Name:        l.Name,
+		Description: l.Description,
+		Private:     l.Private,
+	}
+}
+
+// listParameters is what an owner supplies when creating or updating a
+// list.
+type listParameters struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+// check trims the name and checks the lengths of the name and description.
+func (p *listParameters) check() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	if chirptext.Length(p.Name) > maxListNameLength {
+		return fmt.Errorf("name can be at most %d characters", maxListNameLength)
+	}
+	if chirptext.Length(p.Description) > maxListDescriptionLength {
+		return fmt.Errorf("description can be at most %d characters", maxListDescriptionLength)
+	}
+	return nil
+}
+
+// viewableList reads the list named in the path, responding with an error
+// if it's missing. Private lists are reported as not found to everyone but
+// their owner.
+func (cfg *apiConfig) viewableList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return database.List{}, false
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err == nil && list.Private && list.OwnerID != cfg.viewerID(r) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find list", err)
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// ownedList reads the authenticated user and the list named in the path,
+// responding with an error unless the list exists and belongs to them.
+func (cfg *apiConfig) ownedList(w http.ResponseWriter, r *http.Request) (database.List, bool) {
+	listID, err := uuid.Parse(r.PathValue("listID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid list ID", err)
+		return database.List{}, false
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return database.List{}, false
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return database.List{}, false
+	}
+
+	list, err := cfg.db.GetList(r.Context(), listID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find list", err)
+		return database.List{}, false
+	}
+	if list.OwnerID != userID {
+		// Someone else's private list doesn't exist as far as they know.
+		if list.Private {
+			respondWithError(w, http.StatusNotFound, "Couldn't find list", sql.ErrNoRows)
+			return database.List{}, false
+		}
+		err := errors.New("you can't change someone else's list")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func (cfg *apiConfig) handlerListsCreate(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := listParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	err = params.check()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	count, err := cfg.db.CountListsByOwner(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count lists", err)
+		return
+	}
+	if count >= maxListsPerUser {
+		err := fmt.Errorf("you can't have more than %d lists", maxListsPerUser)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	list, err := cfg.db.CreateList(r.Context(), database.CreateListParams{
+		OwnerID:     userID,
+		Name:        params.Name,
+		Description: params.Description,
+		Private:     params.Private,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create list", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, listResponse(list))
+}
+
+// handlerUsersListsList returns a user's lists, including their private
+// ones only when they're the one asking.
+func (cfg *apiConfig) handlerUsersListsList(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	dbLists, err := cfg.db.GetListsByOwner(r.Context(), database.GetListsByOwnerParams{
+		OwnerID:        ownerID,
+		IncludePrivate: ownerID == cfg.viewerID(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get lists", err)
+		return
+	}
+
+	lists := []List{}
+	for _, list := range dbLists {
+		lists = append(lists, listResponse(list))
+	}
+
+	respondWithJSON(w, http.StatusOK, lists)
+}
+
+func (cfg *apiConfig) handlerListsGet(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.viewableList(w, r)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, listResponse(list))
+}
+
+func (cfg *apiConfig) handlerListsUpdate(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := listParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	err = params.check()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	list, err = cfg.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:          list.ID,
+		OwnerID:     list.OwnerID,
+		Name:        params.Name,
+		Description: params.Description,
+		Private:     params.Private,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update list", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, listResponse(list))
+}
+
+func (cfg *apiConfig) handlerListsDelete(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteList(r.Context(), database.DeleteListParams{
+		ID:      list.ID,
+		OwnerID: list.OwnerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete list", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListMembersList(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.viewableList(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.db.GetListMembers(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get list members", err)
+		return
+	}
+
+	users := []RelatedUser{}
+	for _, row := range rows {
+		users = append(users, RelatedUser{
+			UserID:    row.UserID,
+			Username:  row.Username.String,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, users)
+}
+
+// handlerListMembersAdd adds a user to a list. Adding someone already on
+// it hands back their membership.
+func (cfg *apiConfig) handlerListMembersAdd(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ListID    uuid.UUID `json:"list_id"`
+		UserID    uuid.UUID `json:"user_id"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	_, err = cfg.db.GetUserByID(r.Context(), memberID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	blocked, err := cfg.db.IsBlocked(r.Context(), database.IsBlockedParams{
+		UserID:  list.OwnerID,
+		OtherID: memberID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check blocks", err)
+		return
+	}
+	if blocked {
+		err := errors.New("you can't add this user to a list")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	// Re-adding a member is fine even when the list is full.
+	status := http.StatusOK
+	member, err := cfg.db.GetListMember(r.Context(), database.GetListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		count, err := cfg.db.CountListMembers(r.Context(), list.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count list members", err)
+			return
+		}
+		if count >= maxListMembers {
+			err := fmt.Errorf("a list can't have more than %d members", maxListMembers)
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+
+		status = http.StatusCreated
+		member, err = cfg.db.AddListMember(r.Context(), database.AddListMemberParams{
+			ListID: list.ID,
+			UserID: memberID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// A concurrent request added them first.
+			status = http.StatusOK
+			member, err = cfg.db.GetListMember(r.Context(), database.GetListMemberParams{
+				ListID: list.ID,
+				UserID: memberID,
+			})
+		}
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't add list member", err)
+		return
+	}
+
+	respondWithJSON(w, status, response{
+		ListID:    member.ListID,
+		UserID:    member.UserID,
+		CreatedAt: member.CreatedAt,
+	})
+}
+
+func (cfg *apiConfig) handlerListMembersRemove(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.ownedList(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	err = cfg.db.DeleteListMember(r.Context(), database.DeleteListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't remove list member", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerListsChirps returns the chirps of a list's members, newest first
+// unless sort=asc, filtered for the viewer the same way as GET /api/chirps.
+func (cfg *apiConfig) handlerListsChirps(w http.ResponseWriter, r *http.Request) {
+	list, ok := cfg.viewableList(w, r)
+	if !ok {
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != sortAscending && sortBy != sortDescending {
+		sortBy = sortDescending
+	}
+
+	dbChirps, err := cfg.db.GetListChirps(r.Context(), list.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get list chirps", err)
+		return
+	}
+
+	chirps, err := cfg.visibleChirpsResponse(r.Context(), dbChirps, cfg.viewerID(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+
+	sortChirps(chirps, sortBy)
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
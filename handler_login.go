@@ -30,17 +30,51 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 
 	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
+		auditErr := cfg.recordAuditEvent(r, auditRecord{
+			Action:   auditLoginFailed,
+			Metadata: map[string]any{"email": params.Email, "reason": "unknown email"},
+		})
+		if auditErr != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", auditErr)
+			return
+		}
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
 	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	if err != nil {
+		auditErr := cfg.recordAuditEvent(r, auditRecord{
+			Action:     auditLoginFailed,
+			TargetType: auditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"email": params.Email, "reason": "incorrect password"},
+		})
+		if auditErr != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", auditErr)
+			return
+		}
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+	err = accountRestriction(user)
+	if err != nil {
+		auditErr := cfg.recordAuditEvent(r, auditRecord{
+			Action:     auditLoginFailed,
+			TargetType: auditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"email": params.Email, "reason": err.Error()},
+		})
+		if auditErr != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", auditErr)
+			return
+		}
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	accessToken, err := cfg.makeAccessToken(user)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error making access JWT", err)
 		return
@@ -51,7 +85,25 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Error making refresh token", err)
 		return
 	}
-	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Logging back in during the grace period cancels a pending deletion.
+	if user.DeleteAfter.Valid {
+		err = qtx.CancelUserDeletion(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't cancel account deletion", err)
+			return
+		}
+	}
+
+	_, err = qtx.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
 		Token:     refreshToken,
 		UserID:    user.ID,
 		ExpiresAt: time.Now().AddDate(0, 0, 60).UTC(),
@@ -61,6 +113,23 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    user.ID,
+		Action:     auditLogin,
+		TargetType: auditTargetUser,
+		TargetID:   user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating refresh token in database", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, response{
 		User: User{
 			ID:          user.ID,
@@ -68,6 +137,8 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 			UpdatedAt:   user.UpdatedAt,
 			Email:       user.Email,
 			IsChirpyRed: user.IsChirpyRed,
+			Username:    user.Username.String,
+			Protected:   user.Protected,
 		},
 		Token:        accessToken,
 		RefreshToken: refreshToken,
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/blobstore"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMediaUploadBytes = 5 << 20
+	maxChirpMedia       = 4
+	maxAltTextLength    = 1000
+
+	// unattachedMediaRetention is how long an upload can wait to be attached
+	// to a chirp before purgeUnattachedMedia removes it. Media whose chirp
+	// has been deleted for good is removed the same way.
+	unattachedMediaRetention = 24 * time.Hour
+)
+
+var (
+	errTooManyMedia   = fmt.Errorf("a chirp can have at most %d attachments", maxChirpMedia)
+	errAltTextTooLong = fmt.Errorf("alt text can be at most %d characters", maxAltTextLength)
+	errMediaNotFound  = errors.New("couldn't find media to attach")
+)
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	AltText      string    `json:"alt_text"`
+}
+
+// MediaAttachment links an uploaded file to a chirp being created.
+type MediaAttachment struct {
+	ID      uuid.UUID `json:"id"`
+	AltText string    `json:"alt_text"`
+}
+
+func mediaResponse(m database.Media) Media {
+	return Media{
+		ID:           m.ID,
+		URL:          "/media/" + m.StorageKey,
+		ThumbnailURL: "/media/" + m.ThumbnailKey,
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+		AltText:      m.AltText,
+	}
+}
+
+// handlerMediaUpload accepts a single image in the "file" field of a
+// multipart form. The image is stored re-encoded, never as uploaded.
+func (cfg *apiConfig) handlerMediaUpload(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	// Leave room for the multipart headers around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaUploadBytes+1<<20)
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read file", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaUploadBytes+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read file", err)
+		return
+	}
+	if len(data) > maxMediaUploadBytes {
+		err := fmt.Errorf("file exceeds %d bytes", maxMediaUploadBytes)
+		respondWithError(w, http.StatusRequestEntityTooLarge, err.Error(), err)
+		return
+	}
+
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Only JPEG, PNG and GIF images are supported", err)
+		return
+	}
+	if errors.Is(err, media.ErrTooLarge) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Image dimensions are too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't process image", err)
+		return
+	}
+
+	id := uuid.New()
+	storageKey := id.String() + media.Extensions[img.ContentType]
+	thumbnailKey := id.String() + "_thumb" + media.Extensions[img.Thumbnail.ContentType]
+
+	err = cfg.blobs.Put(r.Context(), storageKey, bytes.NewReader(img.Data))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error storing file", err)
+		return
+	}
+	err = cfg.blobs.Put(r.Context(), thumbnailKey, bytes.NewReader(img.Thumbnail.Data))
+	if err != nil {
+		cfg.deleteBlobs(r.Context(), storageKey)
+		respondWithError(w, http.StatusInternalServerError, "Error storing file", err)
+		return
+	}
+
+	dbMedia, err := cfg.db.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:           id,
+		UserID:       uuid.NullUUID{UUID: userID, Valid: true},
+		ContentType:  img.ContentType,
+		ByteSize:     int32(len(img.Data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.deleteBlobs(r.Context(), storageKey, thumbnailKey)
+		respondWithError(w, http.StatusInternalServerError, "Error saving media", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mediaResponse(dbMedia))
+}
+
+// handlerMediaServe serves stored media to those who can see it: only
+// the uploader until it's attached, then the audience of its chirp. Files
+// are named by a random ID and never change, so media on public chirps can
+// be cached indefinitely.
+func (cfg *apiConfig) handlerMediaServe(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	dbMedia, err := cfg.db.GetMediaByKey(r.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting media %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	visible, public, err := canViewMedia(r.Context(), cfg.db, dbMedia, cfg.viewerID(r))
+	if err != nil {
+		log.Printf("Error checking access to media %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !visible {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := cfg.blobs.Open(r.Context(), key)
+	if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error opening media %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	cacheControl := "private, no-cache"
+	if public {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, key, time.Time{}, f)
+}
+
+// canViewMedia reports whether viewerID can see m, and whether everyone
+// can. Media on a deleted chirp is visible to no one, and media on a chirp
+// moderators have hidden only to its author.
+func canViewMedia(ctx context.Context, q *database.Queries, m database.Media, viewerID uuid.UUID) (bool, bool, error) {
+	if !m.ChirpID.Valid {
+		return viewerID != uuid.Nil && m.UserID.Valid && m.UserID.UUID == viewerID, false, nil
+	}
+
+	chirp, err := q.GetChirpByID(ctx, m.ChirpID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	public, err := isPublicChirp(ctx, q, chirp)
+	if err != nil || public {
+		return public, public, err
+	}
+	visible, err := canViewChirp(ctx, q, chirp, viewerID)
+	return visible, false, err
+}
+
+// attachChirpMedia links an author's uploads to their new chirp in the
+// order given. Each upload can only be attached once.
+func attachChirpMedia(ctx context.Context, q *database.Queries, chirp database.Chirp, attachments []MediaAttachment) error {
+	for i, attachment := range attachments {
+		_, err := q.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			AltText:  attachment.AltText,
+			ID:       attachment.ID,
+			UserID:   chirp.UserID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", errMediaNotFound, attachment.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't attach media: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateAttachments(attachments []MediaAttachment) error {
+	if len(attachments) > maxChirpMedia {
+		return errTooManyMedia
+	}
+	for _, attachment := range attachments {
+		if utf8.RuneCountInString(attachment.AltText) > maxAltTextLength {
+			return errAltTextTooLong
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		err := cfg.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("Error deleting blob %s: %v", key, err)
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeUnattachedMedia(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := cfg.db.PurgeUnattachedMedia(ctx, time.Now().Add(-unattachedMediaRetention).UTC())
+		if err != nil {
+			log.Printf("Error purging unattached media: %v", err)
+		} else if len(purged) > 0 {
+			for _, m := range purged {
+				cfg.deleteBlobs(ctx, m.StorageKey, m.ThumbnailKey)
+			}
+			log.Printf("Purged %d unattached media", len(purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,722 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxConversationMembers includes whoever starts the conversation.
+	maxConversationMembers = 10
+
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 100
+
+	// notificationMessage is sent over the notification stream to the other
+	// members of a conversation when a message arrives.
+	notificationMessage = "message.created"
+)
+
+var (
+	errMessageBlocked       = errors.New("you can't message this user")
+	errMessageFollowersOnly = errors.New("this user only accepts messages from their followers")
+)
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+func messageResponse(m database.Message) Message {
+	return Message{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+	}
+}
+
+// ConversationMember is a member of a conversation. Messages sent up to
+// last_read_at have been read by them.
+type ConversationMember struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Username   string    `json:"username,omitempty"`
+	JoinedAt   time.Time `json:"joined_at"`
+	LastReadAt time.Time `json:"last_read_at"`
+}
+
+type Conversation struct {
+	ID          uuid.UUID            `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Group       bool                 `json:"group"`
+	Members     []ConversationMember `json:"members"`
+	LastMessage *Message             `json:"last_message"`
+	UnreadCount int64                `json:"unread_count"`
+}
+
+// directConversationKey names the one-to-one conversation between two
+// users, whichever of them starts it.
+func directConversationKey(a, b uuid.UUID) string {
+	if a.String() > b.String() {
+		a, b = b, a
+	}
+	return a.String() + ":" + b.String()
+}
+
+// checkCanMessage returns errMessageBlocked if a block stands between
+// sender and recipient, or errMessageFollowersOnly if the recipient only
+// takes messages from followers and sender isn't one.
+func checkCanMessage(ctx context.Context, q *database.Queries, senderID uuid.UUID, recipient database.User) error {
+	blocked, err := q.IsBlocked(ctx, database.IsBlockedParams{
+		UserID:  senderID,
+		OtherID: recipient.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errMessageBlocked
+	}
+
+	if !recipient.DmsFollowersOnly {
+		return nil
+	}
+	_, err = q.GetFollow(ctx, database.GetFollowParams{
+		FollowerID: senderID,
+		FolloweeID: recipient.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return errMessageFollowersOnly
+	}
+	return err
+}
+
+// respondWithMessageError reports a failure to start a conversation or
+// send a message.
+func respondWithMessageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errMessageBlocked), errors.Is(err, errMessageFollowersOnly):
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+	}
+}
+
+// conversationsResponse builds API conversations for userID, looking up
+// members and last messages in batches. The last message is the newest one
+// not from a user userID has blocked, or who blocked them.
+func (cfg *apiConfig) conversationsResponse(ctx context.Context, userID uuid.UUID, rows []database.GetConversationsRow) ([]Conversation, error) {
+	conversationIDs := []uuid.UUID{}
+	for _, row := range rows {
+		conversationIDs = append(conversationIDs, row.Conversation.ID)
+	}
+
+	dbMembers, err := cfg.db.GetConversationMembers(ctx, conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+	members := map[uuid.UUID][]ConversationMember{}
+	for _, m := range dbMembers {
+		members[m.ConversationID] = append(members[m.ConversationID], ConversationMember{
+			UserID:     m.UserID,
+			Username:   m.Username.String,
+			JoinedAt:   m.JoinedAt,
+			LastReadAt: m.LastReadAt,
+		})
+	}
+
+	dbLatest, err := cfg.db.GetLatestMessages(ctx, database.GetLatestMessagesParams{
+		ConversationIds: conversationIDs,
+		UserID:          userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	latest := map[uuid.UUID]Message{}
+	for _, m := range dbLatest {
+		latest[m.ConversationID] = messageResponse(m)
+	}
+
+	conversations := []Conversation{}
+	for _, row := range rows {
+		conversation := Conversation{
+			ID:          row.Conversation.ID,
+			CreatedAt:   row.Conversation.CreatedAt,
+			UpdatedAt:   row.Conversation.UpdatedAt,
+			Group:       !row.Conversation.DirectKey.Valid,
+			Members:     members[row.Conversation.ID],
+			UnreadCount: row.UnreadCount,
+		}
+		if m, ok := latest[row.Conversation.ID]; ok {
+			conversation.LastMessage = &m
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// conversationResponse is conversationsResponse for a single conversation.
+func (cfg *apiConfig) conversationResponse(ctx context.Context, userID uuid.UUID, conversation database.Conversation) (Conversation, error) {
+	unread, err := cfg.db.CountUnreadMessages(ctx, database.CountUnreadMessagesParams{
+		UserID:         userID,
+		ConversationID: uuid.NullUUID{UUID: conversation.ID, Valid: true},
+	})
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	conversations, err := cfg.conversationsResponse(ctx, userID, []database.GetConversationsRow{{
+		Conversation: conversation,
+		UnreadCount:  unread,
+	}})
+	if err != nil {
+		return Conversation{}, err
+	}
+	return conversations[0], nil
+}
+
+// conversationMember reads the authenticated user and the conversation
+// named in the path, responding with an error if either is missing. A
+// conversation the user isn't a member of is reported as not found.
+func (cfg *apiConfig) conversationMember(w http.ResponseWriter, r *http.Request) (userID uuid.UUID, conversation database.Conversation, ok bool) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid conversation ID", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	userID, err = auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+
+	conversation, err = cfg.db.GetConversationForMember(r.Context(), database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find conversation", err)
+		return uuid.Nil, database.Conversation{}, false
+	}
+	return userID, conversation, true
+}
+
+// handlerConversationsCreate starts a conversation with user_ids. With one
+// other user it's a one-to-one conversation, and starting it again hands
+// back the existing one.
+func (cfg *apiConfig) handlerConversationsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		UserIDs []uuid.UUID `json:"user_ids"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	recipientIDs := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.UserIDs {
+		if !seen[id] {
+			seen[id] = true
+			recipientIDs = append(recipientIDs, id)
+		}
+	}
+	if len(recipientIDs) == 0 {
+		err := errors.New("user_ids must name someone other than yourself")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if len(recipientIDs)+1 > maxConversationMembers {
+		err := fmt.Errorf("conversations can't have more than %d members", maxConversationMembers)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	for _, id := range recipientIDs {
+		recipient, err := cfg.db.GetUserByID(r.Context(), id)
+		if err == nil {
+			err = checkCanMessage(r.Context(), cfg.db, userID, recipient)
+		}
+		if err != nil {
+			respondWithMessageError(w, err)
+			return
+		}
+	}
+
+	directKey := sql.NullString{}
+	if len(recipientIDs) == 1 {
+		directKey = sql.NullString{String: directConversationKey(userID, recipientIDs[0]), Valid: true}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	status := http.StatusCreated
+	conversation, err := qtx.CreateConversation(r.Context(), database.CreateConversationParams{
+		CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+		DirectKey: directKey,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusOK
+		conversation, err = qtx.GetConversationByDirectKey(r.Context(), directKey)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+
+	if status == http.StatusCreated {
+		for _, id := range append([]uuid.UUID{userID}, recipientIDs...) {
+			err = qtx.AddConversationMember(r.Context(), database.AddConversationMemberParams{
+				ConversationID: conversation.ID,
+				UserID:         id,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+				return
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating conversation", err)
+		return
+	}
+
+	resp, err := cfg.conversationResponse(r.Context(), userID, conversation)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building conversation response", err)
+		return
+	}
+
+	respondWithJSON(w, status, resp)
+}
+
+// handlerConversationsList lists the user's conversations, most recently
+// active first.
+func (cfg *apiConfig) handlerConversationsList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	rows, err := cfg.db.GetConversations(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get conversations", err)
+		return
+	}
+
+	conversations, err := cfg.conversationsResponse(r.Context(), userID, rows)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building conversation response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, conversations)
+}
+
+func (cfg *apiConfig) handlerConversationsGet(w http.ResponseWriter, r *http.Request) {
+	userID, conversation, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := cfg.conversationResponse(r.Context(), userID, conversation)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building conversation response", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerConversationsUnread counts the user's unread messages across all
+// their conversations.
+func (cfg *apiConfig) handlerConversationsUnread(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		UnreadCount int64 `json:"unread_count"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadMessages(r.Context(), database.CountUnreadMessagesParams{
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count unread messages", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{UnreadCount: unread})
+}
+
+// handlerMessagesList returns a conversation's messages newest first,
+// paged like notifications. Messages from users the caller has blocked,
+// or who blocked them, are left out, so a page can come back short.
+func (cfg *apiConfig) handlerMessagesList(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Messages   []Message  `json:"messages"`
+		NextBefore *uuid.UUID `json:"next_before,omitempty"`
+	}
+
+	userID, conversation, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := queryLimit(r, defaultMessagesLimit, maxMessagesLimit)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	before := uuid.NullUUID{}
+	if s := r.URL.Query().Get("before"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid before ID", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbMessages, err := cfg.db.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID: conversation.ID,
+		Before:         before,
+		MaxResults:     int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get messages", err)
+		return
+	}
+
+	relations, err := userRelations(r.Context(), cfg.db, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get blocks", err)
+		return
+	}
+
+	resp := response{Messages: []Message{}}
+	for _, m := range dbMessages {
+		if relations.Blocked(m.SenderID) {
+			continue
+		}
+		resp.Messages = append(resp.Messages, messageResponse(m))
+	}
+	if len(dbMessages) == limit {
+		resp.NextBefore = &dbMessages[len(dbMessages)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// handlerMessagesCreate sends a message to a conversation. Bodies go
+// through the same length and moderation checks as chirps, though messages
+// that would be flagged for review aren't, since moderators don't read
+// private messages. Recipients' blocks and message settings are checked
+// again on every message, since they may have changed since the
+// conversation started. In a one-to-one conversation that refuses the
+// message; in a group it's only delivered to the members who'd accept it,
+// and members with a block either way never see it in the conversation.
+func (cfg *apiConfig) handlerMessagesCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userID, conversation, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	sender, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	members, err := cfg.db.GetConversationMembers(r.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get conversation members", err)
+		return
+	}
+	recipientIDs := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID == userID {
+			continue
+		}
+		recipient, err := cfg.db.GetUserByID(r.Context(), member.UserID)
+		if err == nil {
+			err = checkCanMessage(r.Context(), cfg.db, userID, recipient)
+		}
+		refused := errors.Is(err, errMessageBlocked) || errors.Is(err, errMessageFollowersOnly)
+		if refused && !conversation.DirectKey.Valid {
+			continue
+		}
+		if err != nil {
+			respondWithMessageError(w, err)
+			return
+		}
+		recipientIDs = append(recipientIDs, member.UserID)
+	}
+
+	if params.Body == "" {
+		respondWithChirpError(w, errChirpEmpty)
+		return
+	}
+	moderated, err := cfg.checkChirp(params.Body, sender.IsChirpyRed)
+	if isChirpCheckError(err) {
+		respondWithChirpError(w, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	message, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           moderated.Text,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	err = qtx.TouchConversation(r.Context(), conversation.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	// Senders have read their own messages.
+	err = qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ReadAt:         message.CreatedAt,
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	// Notifications are delivered when the transaction commits.
+	for _, recipientID := range recipientIDs {
+		err = notifyMessage(r.Context(), qtx, recipientID, message)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending message", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, messageResponse(message))
+}
+
+// notifyMessage tells userID's open streams about a new message. Unlike
+// other notifications, messages aren't stored in the notifications list.
+func notifyMessage(ctx context.Context, q *database.Queries, userID uuid.UUID, message database.Message) error {
+	type notification struct {
+		Type    string    `json:"type"`
+		UserID  uuid.UUID `json:"user_id"`
+		Message Message   `json:"message"`
+	}
+
+	payload, err := json.Marshal(notification{
+		Type:    notificationMessage,
+		UserID:  userID,
+		Message: messageResponse(message),
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyUser(ctx, string(payload))
+}
+
+// handlerConversationsRead marks the conversation read up to message_id,
+// or up to its latest message if that's left out. The other members see
+// this as a read receipt.
+func (cfg *apiConfig) handlerConversationsRead(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MessageID *uuid.UUID `json:"message_id"`
+	}
+
+	userID, conversation, ok := cfg.conversationMember(w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		err := decoder.Decode(&params)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+			return
+		}
+	}
+
+	var message database.Message
+	var err error
+	if params.MessageID != nil {
+		message, err = cfg.db.GetMessage(r.Context(), database.GetMessageParams{
+			ID:             *params.MessageID,
+			ConversationID: conversation.ID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Couldn't find message", err)
+			return
+		}
+	} else {
+		latest, err := cfg.db.GetLatestMessages(r.Context(), database.GetLatestMessagesParams{
+			ConversationIds: []uuid.UUID{conversation.ID},
+			UserID:          userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get messages", err)
+			return
+		}
+		if len(latest) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		message = latest[0]
+	}
+
+	err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ReadAt:         message.CreatedAt,
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking conversation read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUsersMessageSettings sets whether the authenticated user only
+// takes messages from their followers.
+func (cfg *apiConfig) handlerUsersMessageSettings(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		FollowersOnly bool `json:"followers_only"`
+	}
+	type response struct {
+		FollowersOnly bool `json:"followers_only"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	user, err := cfg.db.SetUserDMsFollowersOnly(r.Context(), database.SetUserDMsFollowersOnlyParams{
+		ID:               userID,
+		DmsFollowersOnly: params.FollowersOnly,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update message settings", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{FollowersOnly: user.DmsFollowersOnly})
+}
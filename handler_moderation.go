@@ -0,0 +1,193 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+type ModerationTerm struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+}
+
+func (cfg *apiConfig) handlerModerationTermsList(w http.ResponseWriter, r *http.Request) {
+	type configTerm struct {
+		Kind    moderation.Kind   `json:"kind"`
+		Pattern string            `json:"pattern"`
+		Action  moderation.Action `json:"action"`
+	}
+	type response struct {
+		Config   []configTerm     `json:"config"`
+		Database []ModerationTerm `json:"database"`
+	}
+
+	dbTerms, err := cfg.db.GetModerationTerms(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation terms", err)
+		return
+	}
+
+	resp := response{
+		Config:   []configTerm{},
+		Database: []ModerationTerm{},
+	}
+	for _, term := range cfg.moderationWords {
+		resp.Config = append(resp.Config, configTerm{
+			Kind:    term.Kind,
+			Pattern: term.Pattern,
+			Action:  term.Action,
+		})
+	}
+	for _, term := range dbTerms {
+		resp.Database = append(resp.Database, ModerationTerm{
+			ID:        term.ID,
+			CreatedAt: term.CreatedAt,
+			UpdatedAt: term.UpdatedAt,
+			Kind:      term.Kind,
+			Pattern:   term.Pattern,
+			Action:    term.Action,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerModerationTermsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Kind    moderation.Kind   `json:"kind"`
+		Pattern string            `json:"pattern"`
+		Action  moderation.Action `json:"action"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if params.Kind == "" {
+		params.Kind = moderation.KindWord
+	}
+	if params.Pattern == "" {
+		err := errors.New("pattern field is empty")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// Building a one-term pipeline validates the kind, action and regex
+	// before anything reaches the database.
+	_, err = moderation.Build([]moderation.Term{{
+		Kind:    params.Kind,
+		Pattern: params.Pattern,
+		Action:  params.Action,
+	}})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	term, err := cfg.db.CreateModerationTerm(r.Context(), database.CreateModerationTermParams{
+		Kind:    string(params.Kind),
+		Pattern: params.Pattern,
+		Action:  string(params.Action),
+	})
+	if isUniqueViolation(err, "moderation_terms_kind_pattern_key") {
+		err := errors.New("a term with this kind and pattern already exists")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create moderation term", err)
+		return
+	}
+
+	err = cfg.reloadModeration(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation terms", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, ModerationTerm{
+		ID:        term.ID,
+		CreatedAt: term.CreatedAt,
+		UpdatedAt: term.UpdatedAt,
+		Kind:      term.Kind,
+		Pattern:   term.Pattern,
+		Action:    term.Action,
+	})
+}
+
+func (cfg *apiConfig) handlerModerationTermsDelete(w http.ResponseWriter, r *http.Request) {
+	termID, err := uuid.Parse(r.PathValue("termID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid term ID", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteModerationTerm(r.Context(), termID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete moderation term", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find moderation term", sql.ErrNoRows)
+		return
+	}
+
+	err = cfg.reloadModeration(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload moderation terms", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ChirpFlag struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	Reasons   []string   `json:"reasons"`
+	CaseID    *uuid.UUID `json:"case_id"`
+}
+
+func chirpFlagResponse(f database.ChirpFlag) ChirpFlag {
+	flag := ChirpFlag{
+		ID:        f.ID,
+		CreatedAt: f.CreatedAt,
+		ChirpID:   f.ChirpID,
+		Reasons:   f.Reasons,
+	}
+	if f.CaseID.Valid {
+		flag.CaseID = &f.CaseID.UUID
+	}
+	return flag
+}
+
+func (cfg *apiConfig) handlerChirpFlagsList(w http.ResponseWriter, r *http.Request) {
+	dbFlags, err := cfg.db.GetChirpFlags(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get flagged chirps", err)
+		return
+	}
+
+	flags := []ChirpFlag{}
+	for _, f := range dbFlags {
+		flags = append(flags, chirpFlagResponse(f))
+	}
+
+	respondWithJSON(w, http.StatusOK, flags)
+}
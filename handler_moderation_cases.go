@@ -0,0 +1,532 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Case statuses.
+const (
+	caseOpen     = "open"
+	caseClaimed  = "claimed"
+	caseResolved = "resolved"
+)
+
+// Moderation decisions. The last three resolve a case.
+const (
+	decisionClaim         = "claim"
+	decisionRelease       = "release"
+	decisionDismiss       = "dismiss"
+	decisionHideChirp     = "hide_chirp"
+	decisionSuspendAuthor = "suspend_author"
+)
+
+const (
+	defaultModerationCasesLimit = 50
+	maxModerationCasesLimit     = 200
+)
+
+type ModerationCase struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ChirpID     uuid.UUID  `json:"chirp_id"`
+	Status      string     `json:"status"`
+	ClaimedBy   *uuid.UUID `json:"claimed_by"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+	Resolution  string     `json:"resolution,omitempty"`
+	ResolvedBy  *uuid.UUID `json:"resolved_by"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	ReportCount int64      `json:"report_count"`
+	Flagged     bool       `json:"flagged"`
+}
+
+func moderationCaseResponse(c database.ModerationCase) ModerationCase {
+	modCase := ModerationCase{
+		ID:         c.ID,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		ChirpID:    c.ChirpID,
+		Status:     c.Status,
+		Resolution: c.Resolution.String,
+	}
+	if c.ClaimedBy.Valid {
+		modCase.ClaimedBy = &c.ClaimedBy.UUID
+	}
+	if c.ClaimedAt.Valid {
+		modCase.ClaimedAt = &c.ClaimedAt.Time
+	}
+	if c.ResolvedBy.Valid {
+		modCase.ResolvedBy = &c.ResolvedBy.UUID
+	}
+	if c.ResolvedAt.Valid {
+		modCase.ResolvedAt = &c.ResolvedAt.Time
+	}
+	return modCase
+}
+
+type ModerationDecision struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	Action      string     `json:"action"`
+	Note        string     `json:"note"`
+}
+
+// handlerModerationCasesList returns the moderation queue, oldest first.
+// By default it lists every unresolved case; status picks one status and
+// claimed_by picks the cases a moderator is working on.
+func (cfg *apiConfig) handlerModerationCasesList(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r, defaultModerationCasesLimit, maxModerationCasesLimit)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	params := database.GetModerationCasesParams{MaxResults: int32(limit)}
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case caseOpen, caseClaimed, caseResolved:
+		params.Status = sql.NullString{String: status, Valid: true}
+	default:
+		err := fmt.Errorf("invalid status %q", status)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if s := r.URL.Query().Get("claimed_by"); s != "" {
+		claimedBy, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid claimed_by", err)
+			return
+		}
+		params.ClaimedBy = uuid.NullUUID{UUID: claimedBy, Valid: true}
+	}
+
+	rows, err := cfg.db.GetModerationCases(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation cases", err)
+		return
+	}
+
+	cases := []ModerationCase{}
+	for _, row := range rows {
+		modCase := moderationCaseResponse(row.ModerationCase)
+		modCase.ReportCount = row.ReportCount
+		modCase.Flagged = row.Flagged
+		cases = append(cases, modCase)
+	}
+
+	respondWithJSON(w, http.StatusOK, cases)
+}
+
+// handlerModerationCasesGet returns a case with the chirp it's about, even
+// if deleted, and its reports, flags and decision history.
+func (cfg *apiConfig) handlerModerationCasesGet(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ModerationCase
+		Chirp     Chirp                `json:"chirp"`
+		Reports   []Report             `json:"reports"`
+		Flags     []ChirpFlag          `json:"flags"`
+		Decisions []ModerationDecision `json:"decisions"`
+	}
+
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid case ID", err)
+		return
+	}
+
+	dbCase, err := cfg.db.GetModerationCase(r.Context(), caseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find moderation case", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation case", err)
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirpByIDWithDeleted(r.Context(), dbCase.ChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+	chirp, err := cfg.chirpResponse(r.Context(), dbChirp, uuid.Nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	dbReports, err := cfg.db.GetCaseReports(r.Context(), caseID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get reports", err)
+		return
+	}
+	dbFlags, err := cfg.db.GetCaseChirpFlags(r.Context(), uuid.NullUUID{UUID: caseID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get flags", err)
+		return
+	}
+	dbDecisions, err := cfg.db.GetModerationDecisions(r.Context(), caseID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get decisions", err)
+		return
+	}
+
+	resp := response{
+		ModerationCase: moderationCaseResponse(dbCase),
+		Chirp:          chirp,
+		Reports:        []Report{},
+		Flags:          []ChirpFlag{},
+		Decisions:      []ModerationDecision{},
+	}
+	resp.ReportCount = int64(len(dbReports))
+	resp.Flagged = len(dbFlags) > 0
+	for _, report := range dbReports {
+		resp.Reports = append(resp.Reports, reportResponse(report))
+	}
+	for _, flag := range dbFlags {
+		resp.Flags = append(resp.Flags, chirpFlagResponse(flag))
+	}
+	for _, d := range dbDecisions {
+		decision := ModerationDecision{
+			ID:        d.ID,
+			CreatedAt: d.CreatedAt,
+			Action:    d.Action,
+			Note:      d.Note,
+		}
+		if d.ModeratorID.Valid {
+			decision.ModeratorID = &d.ModeratorID.UUID
+		}
+		resp.Decisions = append(resp.Decisions, decision)
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// lockModerationCase loads the case named in the path for update within
+// qtx's transaction, responding with an error if it doesn't exist.
+func lockModerationCase(w http.ResponseWriter, r *http.Request, qtx *database.Queries) (database.ModerationCase, bool) {
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid case ID", err)
+		return database.ModerationCase{}, false
+	}
+
+	modCase, err := qtx.GetModerationCaseForUpdate(r.Context(), caseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find moderation case", err)
+		return database.ModerationCase{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get moderation case", err)
+		return database.ModerationCase{}, false
+	}
+	return modCase, true
+}
+
+// handlerModerationCasesClaim assigns an open case to the caller so two
+// moderators don't work on it at once. Claiming a case you already hold
+// does nothing.
+func (cfg *apiConfig) handlerModerationCasesClaim(w http.ResponseWriter, r *http.Request) {
+	actor := requestAccessToken(r)
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	modCase, ok := lockModerationCase(w, r, qtx)
+	if !ok {
+		return
+	}
+
+	switch {
+	case modCase.Status == caseResolved:
+		err := errors.New("case is already resolved")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	case modCase.Status == caseClaimed && modCase.ClaimedBy.UUID == actor.UserID:
+		respondWithJSON(w, http.StatusOK, moderationCaseResponse(modCase))
+		return
+	case modCase.Status == caseClaimed:
+		err := errors.New("case is claimed by another moderator")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	}
+
+	modCase, err = qtx.ClaimModerationCase(r.Context(), database.ClaimModerationCaseParams{
+		ID:        modCase.ID,
+		ClaimedBy: uuid.NullUUID{UUID: actor.UserID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim moderation case", err)
+		return
+	}
+
+	_, err = qtx.CreateModerationDecision(r.Context(), database.CreateModerationDecisionParams{
+		CaseID:      modCase.ID,
+		ModeratorID: uuid.NullUUID{UUID: actor.UserID, Valid: true},
+		Action:      decisionClaim,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim moderation case", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim moderation case", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, moderationCaseResponse(modCase))
+}
+
+// handlerModerationCasesRelease puts a claimed case back in the queue.
+// Admins can release any moderator's claim.
+func (cfg *apiConfig) handlerModerationCasesRelease(w http.ResponseWriter, r *http.Request) {
+	actor := requestAccessToken(r)
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	modCase, ok := lockModerationCase(w, r, qtx)
+	if !ok {
+		return
+	}
+
+	if modCase.Status != caseClaimed {
+		err := errors.New("case isn't claimed")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	}
+	if modCase.ClaimedBy.UUID != actor.UserID && !actor.HasRole(auth.RoleAdmin) {
+		err := errors.New("case is claimed by another moderator")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	modCase, err = qtx.ReleaseModerationCase(r.Context(), modCase.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't release moderation case", err)
+		return
+	}
+
+	_, err = qtx.CreateModerationDecision(r.Context(), database.CreateModerationDecisionParams{
+		CaseID:      modCase.ID,
+		ModeratorID: uuid.NullUUID{UUID: actor.UserID, Valid: true},
+		Action:      decisionRelease,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't release moderation case", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't release moderation case", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, moderationCaseResponse(modCase))
+}
+
+// handlerModerationCasesResolve closes a case the caller has claimed by
+// dismissing it, hiding the chirp or suspending its author until the given
+// time. The note is kept in the decision history and, for a suspension,
+// used as the reason.
+func (cfg *apiConfig) handlerModerationCasesResolve(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action string     `json:"action"`
+		Note   string     `json:"note"`
+		Until  *time.Time `json:"until"`
+	}
+
+	actor := requestAccessToken(r)
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	switch params.Action {
+	case decisionDismiss, decisionHideChirp:
+	case decisionSuspendAuthor:
+		if params.Until == nil || !params.Until.After(time.Now()) {
+			err := errors.New("until must be in the future")
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	default:
+		err := fmt.Errorf("action must be one of %s, %s or %s", decisionDismiss, decisionHideChirp, decisionSuspendAuthor)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	modCase, ok := lockModerationCase(w, r, qtx)
+	if !ok {
+		return
+	}
+
+	switch {
+	case modCase.Status == caseResolved:
+		err := errors.New("case is already resolved")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	case modCase.Status == caseOpen:
+		err := errors.New("claim the case before resolving it")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	case modCase.ClaimedBy.UUID != actor.UserID:
+		err := errors.New("case is claimed by another moderator")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	var audited auditRecord
+	switch params.Action {
+	case decisionHideChirp:
+		// A chirp its author has already deleted was retracted then.
+		shown, err := qtx.GetChirpByID(r.Context(), modCase.ChirpID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+			return
+		}
+		retract := err == nil && !shown.HiddenAt.Valid
+		public := false
+		if retract {
+			public, err = isPublicChirp(r.Context(), qtx, shown)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+				return
+			}
+		}
+
+		chirp, err := qtx.HideChirp(r.Context(), modCase.ChirpID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't hide chirp", err)
+			return
+		}
+		if retract {
+			err = announceChirpDeleted(r.Context(), qtx, chirp, public)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't hide chirp", err)
+				return
+			}
+		}
+		audited = auditRecord{
+			Action:     auditChirpHidden,
+			TargetType: auditTargetChirp,
+			TargetID:   chirp.ID,
+			Metadata:   map[string]any{"author_id": chirp.UserID, "case_id": modCase.ID},
+		}
+
+	case decisionSuspendAuthor:
+		chirp, err := qtx.GetChirpByIDWithDeleted(r.Context(), modCase.ChirpID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+			return
+		}
+		author, err := qtx.GetUserByID(r.Context(), chirp.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get author", err)
+			return
+		}
+		err = checkAdminTarget(actor, author)
+		if err != nil {
+			respondWithError(w, http.StatusForbidden, err.Error(), err)
+			return
+		}
+
+		author, err = qtx.SuspendUser(r.Context(), database.SuspendUserParams{
+			ID:               author.ID,
+			SuspendedUntil:   sql.NullTime{Time: params.Until.UTC(), Valid: true},
+			SuspensionReason: sql.NullString{String: params.Note, Valid: params.Note != ""},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+			return
+		}
+		err = qtx.RevokeAllRefreshTokensForUser(r.Context(), author.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error suspending user", err)
+			return
+		}
+		audited = auditRecord{
+			Action:     auditUserSuspended,
+			TargetType: auditTargetUser,
+			TargetID:   author.ID,
+			Metadata: map[string]any{
+				"until":   author.SuspendedUntil.Time,
+				"reason":  params.Note,
+				"case_id": modCase.ID,
+			},
+		}
+	}
+
+	modCase, err = qtx.ResolveModerationCase(r.Context(), database.ResolveModerationCaseParams{
+		ID:         modCase.ID,
+		Resolution: sql.NullString{String: params.Action, Valid: true},
+		ResolvedBy: uuid.NullUUID{UUID: actor.UserID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve moderation case", err)
+		return
+	}
+
+	_, err = qtx.CreateModerationDecision(r.Context(), database.CreateModerationDecisionParams{
+		CaseID:      modCase.ID,
+		ModeratorID: uuid.NullUUID{UUID: actor.UserID, Valid: true},
+		Action:      params.Action,
+		Note:        params.Note,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve moderation case", err)
+		return
+	}
+
+	if audited.Action != "" {
+		audited.ActorID = actor.UserID
+		err = recordAudit(r, qtx, audited)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve moderation case", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, moderationCaseResponse(modCase))
+}
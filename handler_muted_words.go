@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/chonginator/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMutedWords         = 200
+	maxMutedPatternLength = 100
+)
+
+type MutedWord struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Kind      feed.TermKind `json:"kind"`
+	Pattern   string        `json:"pattern"`
+	Action    feed.Action   `json:"action"`
+	ExpiresAt *time.Time    `json:"expires_at"`
+}
+
+func mutedWordResponse(m database.MutedWord) MutedWord {
+	word := MutedWord{
+		ID:        m.ID,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+		Kind:      feed.TermKind(m.Kind),
+		Pattern:   m.Pattern,
+		Action:    feed.Action(m.Action),
+	}
+	if m.ExpiresAt.Valid {
+		word.ExpiresAt = &m.ExpiresAt.Time
+	}
+	return word
+}
+
+// ChirpFilter tells a client that a chirp matched the viewer's muted words
+// and should be shown collapsed.
+type ChirpFilter struct {
+	Action feed.Action `json:"action"`
+	Terms  []string    `json:"terms"`
+}
+
+// userWordFilter compiles userID's unexpired muted words. Anonymous users
+// have none.
+func userWordFilter(ctx context.Context, q *database.Queries, userID uuid.UUID) (*feed.WordFilter, error) {
+	if userID == uuid.Nil {
+		return feed.NewWordFilter(nil), nil
+	}
+
+	dbWords, err := q.GetActiveMutedWords(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := []feed.MutedTerm{}
+	for _, word := range dbWords {
+		terms = append(terms, feed.MutedTerm{
+			Kind:    feed.TermKind(word.Kind),
+			Pattern: word.Pattern,
+			Action:  feed.Action(word.Action),
+		})
+	}
+	return feed.NewWordFilter(terms), nil
+}
+
+// matchMutedWords checks a chirp, and any chirp it shares, against filter.
+func matchMutedWords(filter *feed.WordFilter, chirp Chirp) *ChirpFilter {
+	texts := []string{chirp.Body}
+	for _, embed := range []*ChirpEmbed{chirp.RechirpOf, chirp.QuotedChirp} {
+		if embed != nil && embed.Chirp != nil {
+			texts = append(texts, embed.Chirp.Body)
+		}
+	}
+
+	action, terms := filter.Match(texts...)
+	if action == "" {
+		return nil
+	}
+	return &ChirpFilter{Action: action, Terms: terms}
+}
+
+// mutedWordExpiry checks an expiry given by the client, which must be in
+// the future if it's given at all.
+func mutedWordExpiry(expiresAt *time.Time) (sql.NullTime, error) {
+	if expiresAt == nil {
+		return sql.NullTime{}, nil
+	}
+	if !expiresAt.After(time.Now()) {
+		return sql.NullTime{}, errors.New("expires_at must be in the future")
+	}
+	return sql.NullTime{Time: expiresAt.UTC(), Valid: true}, nil
+}
+
+func (cfg *apiConfig) handlerMutedWordsList(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	dbWords, err := cfg.db.GetActiveMutedWords(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get muted words", err)
+		return
+	}
+
+	words := []MutedWord{}
+	for _, word := range dbWords {
+		words = append(words, mutedWordResponse(word))
+	}
+
+	respondWithJSON(w, http.StatusOK, words)
+}
+
+// handlerMutedWordsCreate mutes a word, phrase or hashtag. Muting one
+// that's already muted updates its action and expiry.
+func (cfg *apiConfig) handlerMutedWordsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Kind      feed.TermKind `json:"kind"`
+		Pattern   string        `json:"pattern"`
+		Action    feed.Action   `json:"action"`
+		ExpiresAt *time.Time    `json:"expires_at"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if params.Kind == "" {
+		params.Kind = feed.KindWord
+	}
+	if params.Action == "" {
+		params.Action = feed.ActionHide
+	}
+	if !params.Kind.Valid() {
+		err := fmt.Errorf("kind must be %s or %s", feed.KindWord, feed.KindHashtag)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if !params.Action.Valid() {
+		err := fmt.Errorf("action must be %s or %s", feed.ActionHide, feed.ActionCollapse)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// Hashtags are stored the way they're looked up, so #Tag and tag are
+	// the same muted hashtag.
+	pattern := params.Pattern
+	empty := len(moderation.NewPhrase(pattern)) == 0
+	if params.Kind == feed.KindHashtag {
+		pattern = chirptext.NormalizeTag(pattern)
+		empty = pattern == ""
+	}
+	if empty {
+		err := errors.New("pattern has nothing to match")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if chirptext.Length(pattern) > maxMutedPatternLength {
+		err := fmt.Errorf("pattern exceeds %d characters", maxMutedPatternLength)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	expiresAt, err := mutedWordExpiry(params.ExpiresAt)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	count, err := cfg.db.CountActiveMutedWords(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count muted words", err)
+		return
+	}
+	if count >= maxMutedWords {
+		err := fmt.Errorf("you can't mute more than %d words", maxMutedWords)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	word, err := cfg.db.UpsertMutedWord(r.Context(), database.UpsertMutedWordParams{
+		UserID:    userID,
+		Kind:      string(params.Kind),
+		Pattern:   pattern,
+		Action:    string(params.Action),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't mute word", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, mutedWordResponse(word))
+}
+
+// handlerMutedWordsUpdate changes a muted word's action and expiry. Leaving
+// out expires_at makes it permanent.
+func (cfg *apiConfig) handlerMutedWordsUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action    feed.Action `json:"action"`
+		ExpiresAt *time.Time  `json:"expires_at"`
+	}
+
+	mutedWordID, err := uuid.Parse(r.PathValue("mutedWordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid muted word ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if !params.Action.Valid() {
+		err := fmt.Errorf("action must be %s or %s", feed.ActionHide, feed.ActionCollapse)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	expiresAt, err := mutedWordExpiry(params.ExpiresAt)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	word, err := cfg.db.UpdateMutedWord(r.Context(), database.UpdateMutedWordParams{
+		ID:        mutedWordID,
+		UserID:    userID,
+		Action:    string(params.Action),
+		ExpiresAt: expiresAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find muted word", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update muted word", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mutedWordResponse(word))
+}
+
+func (cfg *apiConfig) handlerMutedWordsDelete(w http.ResponseWriter, r *http.Request) {
+	mutedWordID, err := uuid.Parse(r.PathValue("mutedWordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid muted word ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+		ID:     mutedWordID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete muted word", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find muted word", sql.ErrNoRows)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// purgeExpiredMutedWords deletes muted words once they've expired. They
+// stop applying as soon as they expire; this just keeps the table small.
+func (cfg *apiConfig) purgeExpiredMutedWords(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.db.DeleteExpiredMutedWords(ctx)
+		if err != nil {
+			log.Printf("Error purging expired muted words: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d expired muted words", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
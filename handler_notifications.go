@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/events"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationMention   = "mention"
+	notificationReply     = "reply"
+	notificationFollow    = "follow"
+	notificationChirpyRed = "chirpy_red"
+
+	defaultNotificationsLimit = 20
+	maxNotificationsLimit     = 100
+)
+
+// notificationTypes lists every type a user can turn on or off.
+var notificationTypes = []string{
+	notificationMention,
+	notificationReply,
+	notificationFollow,
+	notificationChirpyRed,
+}
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Type      string     `json:"type"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+func notificationResponse(n database.Notification) Notification {
+	notification := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		UserID:    n.UserID,
+		Type:      n.Type,
+	}
+	if n.ActorID.Valid {
+		notification.ActorID = &n.ActorID.UUID
+	}
+	if n.ChirpID.Valid {
+		notification.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		notification.ReadAt = &n.ReadAt.Time
+	}
+	return notification
+}
+
+// subscribeNotifications turns domain events into notifications for the
+// users they concern.
+func (cfg *apiConfig) subscribeNotifications(bus *events.Bus) {
+	bus.Subscribe(eventChirpCreated, cfg.notifyChirpCreated)
+	bus.Subscribe(eventUserFollowed, cfg.notifyUserFollowed)
+	bus.Subscribe(eventUserUpgraded, cfg.notifyUserUpgraded)
+}
+
+// notify stores a notification, unless the recipient has turned its type
+// off, was already notified about the event or has blocked or muted the
+// actor, and pushes it to their open WebSocket connections.
+func (cfg *apiConfig) notify(ctx context.Context, params database.CreateNotificationParams) error {
+	if params.ActorID.Valid {
+		relations, err := userRelations(ctx, cfg.db, params.UserID)
+		if err != nil {
+			return err
+		}
+		if relations.Hides(params.ActorID.UUID) {
+			return nil
+		}
+	}
+
+	n, err := cfg.db.CreateNotification(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't create notification: %w", err)
+	}
+
+	payload, err := json.Marshal(notificationResponse(n))
+	if err != nil {
+		return err
+	}
+	return cfg.db.NotifyUser(ctx, string(payload))
+}
+
+// notifyChirpCreated notifies the author of the chirp being replied to and
+// everyone mentioned. Someone who is both only hears about the reply, and
+// nobody hears about a chirp they can't see.
+func (cfg *apiConfig) notifyChirpCreated(ctx context.Context, e events.Event) error {
+	payload := chirpCreatedEvent{}
+	err := e.Decode(&payload)
+	if err != nil {
+		return err
+	}
+
+	chirp, err := cfg.db.GetChirpByID(ctx, payload.ChirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	notified := map[uuid.UUID]bool{chirp.UserID: true}
+	if chirp.ReplyToID.Valid {
+		parent, err := cfg.db.GetChirpByID(ctx, chirp.ReplyToID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		viewable := false
+		if err == nil && !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			viewable, err = canViewChirp(ctx, cfg.db, chirp, parent.UserID)
+			if err != nil {
+				return err
+			}
+		}
+		if viewable {
+			err = cfg.notify(ctx, database.CreateNotificationParams{
+				EventKey: e.Key,
+				UserID:   parent.UserID,
+				Type:     notificationReply,
+				ActorID:  uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+				ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	mentions, err := cfg.db.GetChirpMentionsByChirpIDs(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		if !mention.UserID.Valid || notified[mention.UserID.UUID] {
+			continue
+		}
+		notified[mention.UserID.UUID] = true
+		viewable, err := canViewChirp(ctx, cfg.db, chirp, mention.UserID.UUID)
+		if err != nil {
+			return err
+		}
+		if !viewable {
+			continue
+		}
+		err = cfg.notify(ctx, database.CreateNotificationParams{
+			EventKey: e.Key,
+			UserID:   mention.UserID.UUID,
+			Type:     notificationMention,
+			ActorID:  uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) notifyUserFollowed(ctx context.Context, e events.Event) error {
+	payload := userFollowedEvent{}
+	err := e.Decode(&payload)
+	if err != nil {
+		return err
+	}
+	return cfg.notify(ctx, database.CreateNotificationParams{
+		EventKey: e.Key,
+		UserID:   payload.FolloweeID,
+		Type:     notificationFollow,
+		ActorID:  uuid.NullUUID{UUID: payload.FollowerID, Valid: true},
+	})
+}
+
+func (cfg *apiConfig) notifyUserUpgraded(ctx context.Context, e events.Event) error {
+	payload := userUpgradedEvent{}
+	err := e.Decode(&payload)
+	if err != nil {
+		return err
+	}
+	return cfg.notify(ctx, database.CreateNotificationParams{
+		EventKey: e.Key,
+		UserID:   payload.UserID,
+		Type:     notificationChirpyRed,
+	})
+}
+
+// queryLimit reads the limit query parameter, falling back to def when
+// it's left out.
+func queryLimit(r *http.Request, def, max int) (int, error) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > max {
+		return 0, fmt.Errorf("limit must be between 1 and %d", max)
+	}
+	return limit, nil
+}
+
+// handlerNotificationsList returns the user's notifications newest first.
+// Pages are fetched by passing the next_before of the previous page as
+// before; unread=true skips notifications already read. Notifications from
+// users the caller has since blocked or muted are left out, so a page can
+// come back short.
+func (cfg *apiConfig) handlerNotificationsList(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Notifications []Notification `json:"notifications"`
+		UnreadCount   int64          `json:"unread_count"`
+		NextBefore    *uuid.UUID     `json:"next_before,omitempty"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	limit, err := queryLimit(r, defaultNotificationsLimit, maxNotificationsLimit)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	before := uuid.NullUUID{}
+	if s := query.Get("before"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid before ID", err)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbNotifications, err := cfg.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:     userID,
+		Before:     before,
+		UnreadOnly: query.Get("unread") == "true",
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get notifications", err)
+		return
+	}
+
+	unread, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count unread notifications", err)
+		return
+	}
+
+	relations, err := userRelations(r.Context(), cfg.db, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get blocks", err)
+		return
+	}
+
+	resp := response{
+		Notifications: []Notification{},
+		UnreadCount:   unread,
+	}
+	for _, n := range dbNotifications {
+		if n.ActorID.Valid && relations.Hides(n.ActorID.UUID) {
+			continue
+		}
+		resp.Notifications = append(resp.Notifications, notificationResponse(n))
+	}
+	if len(dbNotifications) == limit {
+		resp.NextBefore = &dbNotifications[len(dbNotifications)-1].ID
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid notification ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	n, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking notification read", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find notification", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerNotificationsReadAll(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	err = cfg.db.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error marking notifications read", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notificationPreferences returns whether each notification type is
+// enabled for a user. Types without a stored preference are enabled.
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := cfg.db.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := map[string]bool{}
+	for _, notificationType := range notificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Type] = preference.Enabled
+	}
+	return preferences, nil
+}
+
+func (cfg *apiConfig) handlerNotificationPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get notification preferences", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
+
+// handlerNotificationPreferencesUpdate takes a map of notification type to
+// whether it's enabled. Types left out keep their current setting.
+func (cfg *apiConfig) handlerNotificationPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := map[string]bool{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	for notificationType := range params {
+		if !slices.Contains(notificationTypes, notificationType) {
+			err := fmt.Errorf("unknown notification type %q", notificationType)
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	for notificationType, enabled := range params {
+		err = qtx.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    notificationType,
+			Enabled: enabled,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving notification preferences", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving notification preferences", err)
+		return
+	}
+
+	preferences, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get notification preferences", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, preferences)
+}
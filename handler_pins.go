@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxPinnedChirps          = 1
+	maxChirpyRedPinnedChirps = 5
+)
+
+func pinnedChirpLimit(isChirpyRed bool) int {
+	if isChirpyRed {
+		return maxChirpyRedPinnedChirps
+	}
+	return maxPinnedChirps
+}
+
+// pinnedChirps returns the chirps authorID has pinned, in order. Pins past
+// the author's current limit are left out, so a user who lets Chirpy Red
+// lapse keeps only their first pin showing.
+func pinnedChirps(ctx context.Context, q *database.Queries, authorID uuid.UUID) ([]database.Chirp, error) {
+	author, err := q.GetUserByID(ctx, authorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return []database.Chirp{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err := q.GetPinnedChirps(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if limit := pinnedChirpLimit(author.IsChirpyRed); len(pinned) > limit {
+		pinned = pinned[:limit]
+	}
+	return pinned, nil
+}
+
+// sortPinnedFirst moves the chirps in pinned to the front of chirps, in
+// the order they were pinned, keeping the rest in their current order.
+func sortPinnedFirst(chirps []Chirp, pinned []database.Chirp) {
+	order := map[uuid.UUID]int{}
+	for i, chirp := range pinned {
+		order[chirp.ID] = i
+	}
+
+	for i := range chirps {
+		_, chirps[i].Pinned = order[chirps[i].ID]
+	}
+
+	slices.SortStableFunc(chirps, func(chirpA, chirpB Chirp) int {
+		switch {
+		case chirpA.Pinned && chirpB.Pinned:
+			return order[chirpA.ID] - order[chirpB.ID]
+		case chirpA.Pinned:
+			return -1
+		case chirpB.Pinned:
+			return 1
+		}
+		return 0
+	})
+}
+
+// handlerChirpsPin pins one of the authenticated user's chirps to their
+// profile, after any chirps already pinned. Pinning a chirp twice hands
+// back the chirp. The user's row stays locked until the pin is written, so
+// concurrent pins can't go over the limit between counting and pinning.
+func (cfg *apiConfig) handlerChirpsPin(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.GetUserByIDForUpdate(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	chirp, err := qtx.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	if chirp.UserID != userID {
+		err := errors.New("you can only pin your own chirps")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+	if chirp.HiddenAt.Valid {
+		err := errors.New("hidden chirps can't be pinned")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	status := http.StatusCreated
+	_, err = qtx.GetPin(r.Context(), database.GetPinParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	switch {
+	case err == nil:
+		status = http.StatusOK
+	case !errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get pin", err)
+		return
+	default:
+		count, err := qtx.CountPinnedChirps(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't count pinned chirps", err)
+			return
+		}
+		if limit := pinnedChirpLimit(user.IsChirpyRed); count >= int64(limit) {
+			err := fmt.Errorf("you can't pin more than %d chirps", limit)
+			respondWithError(w, http.StatusForbidden, err.Error(), err)
+			return
+		}
+
+		_, err = qtx.PinChirp(r.Context(), database.PinChirpParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			status = http.StatusOK
+		} else if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error pinning chirp", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+	resp.Pinned = true
+
+	respondWithJSON(w, status, resp)
+}
+
+func (cfg *apiConfig) handlerChirpsUnpin(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	err = cfg.db.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unpinning chirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerPinsReorder sets the order of the authenticated user's pinned
+// chirps. chirp_ids must list every pinned chirp exactly once.
+func (cfg *apiConfig) handlerPinsReorder(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpIDs []uuid.UUID `json:"chirp_ids"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	pinned, err := qtx.GetPinnedChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get pinned chirps", err)
+		return
+	}
+
+	byID := map[uuid.UUID]database.Chirp{}
+	for _, chirp := range pinned {
+		byID[chirp.ID] = chirp
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range params.ChirpIDs {
+		if _, ok := byID[id]; !ok || seen[id] {
+			err := errors.New("chirp_ids must list each pinned chirp once")
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(byID) {
+		err := errors.New("chirp_ids must list each pinned chirp once")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	ordered := []database.Chirp{}
+	for i, id := range params.ChirpIDs {
+		_, err := qtx.SetPinPosition(r.Context(), database.SetPinPositionParams{
+			UserID:   userID,
+			ChirpID:  id,
+			Position: int32(i),
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+			return
+		}
+		ordered = append(ordered, byID[id])
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reordering pins", err)
+		return
+	}
+
+	chirps, err := cfg.chirpsResponse(r.Context(), ordered, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirps response", err)
+		return
+	}
+	for i := range chirps {
+		chirps[i].Pinned = true
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/moderation"
+	"github.com/chonginator/chirpy/internal/poll"
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	errInvalidPoll   = errors.New("invalid poll")
+	errPollWithMedia = errors.New("a chirp can't have both a poll and media")
+)
+
+// NewPoll is the poll part of a chirp being created.
+type NewPoll struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+// Poll is a chirp's poll as a viewer sees it. Counts are left out until
+// the viewer has voted or the poll has closed.
+type Poll struct {
+	ClosesAt  time.Time    `json:"closes_at"`
+	Closed    bool         `json:"closed"`
+	Options   []PollOption `json:"options"`
+	VoteCount *int32       `json:"vote_count,omitempty"`
+	MyVote    *int32       `json:"my_vote,omitempty"`
+}
+
+type PollOption struct {
+	Position  int32  `json:"position"`
+	Text      string `json:"text"`
+	VoteCount *int32 `json:"vote_count,omitempty"`
+}
+
+// checkPoll validates a new poll, returning its normalized options. Each
+// option goes through the moderation pipeline like a chirp body: masked
+// words are replaced, a rejected option rejects the chirp with
+// errChirpRejected, and the matches of flagged options are returned for
+// the chirp to be flagged on. Other errors wrap errInvalidPoll.
+func (cfg *apiConfig) checkPoll(p NewPoll, now time.Time) ([]string, []moderation.Match, error) {
+	masked := make([]string, 0, len(p.Options))
+	flags := []moderation.Match{}
+	for _, option := range p.Options {
+		moderated := cfg.cleanChirp(norm.NFC.String(option))
+		if moderated.Rejected() {
+			return nil, nil, errChirpRejected
+		}
+		if moderated.Flagged() {
+			flags = append(flags, moderated.Matches...)
+		}
+		masked = append(masked, moderated.Text)
+	}
+
+	options, err := poll.Options(masked)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errInvalidPoll, err)
+	}
+	err = poll.ClosesAt(p.ClosesAt, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errInvalidPoll, err)
+	}
+	return options, flags, nil
+}
+
+// createPoll stores a poll checked by checkPoll for chirp.
+func createPoll(ctx context.Context, q *database.Queries, chirp database.Chirp, options []string, closesAt time.Time) error {
+	_, err := q.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirp.ID,
+		ClosesAt: closesAt.UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create poll: %w", err)
+	}
+
+	for i, option := range options {
+		err = q.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			Text:     option,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't create poll option: %w", err)
+		}
+	}
+	return nil
+}
+
+// pollsResponse looks up the polls of chirpIDs as viewerID sees them,
+// keyed by chirp ID. Chirps without a poll are left out.
+func (cfg *apiConfig) pollsResponse(ctx context.Context, chirpIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	dbPolls, err := cfg.db.GetPollsByChirpIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbPolls) == 0 {
+		return map[uuid.UUID]*Poll{}, nil
+	}
+
+	pollIDs := []uuid.UUID{}
+	for _, p := range dbPolls {
+		pollIDs = append(pollIDs, p.ChirpID)
+	}
+
+	dbOptions, err := cfg.db.GetPollOptionsByChirpIDs(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	options := map[uuid.UUID][]database.PollOption{}
+	for _, option := range dbOptions {
+		options[option.ChirpID] = append(options[option.ChirpID], option)
+	}
+
+	votes := map[uuid.UUID]int32{}
+	if viewerID != uuid.Nil {
+		dbVotes, err := cfg.db.GetPollVotesByUser(ctx, database.GetPollVotesByUserParams{
+			UserID:   viewerID,
+			ChirpIds: pollIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, vote := range dbVotes {
+			votes[vote.ChirpID] = vote.Position
+		}
+	}
+
+	now := time.Now().UTC()
+	polls := map[uuid.UUID]*Poll{}
+	for _, p := range dbPolls {
+		myVote, voted := votes[p.ChirpID]
+		showResults := poll.ShowResults(voted, p.ClosesAt, now)
+
+		resp := &Poll{
+			ClosesAt: p.ClosesAt,
+			Closed:   poll.Closed(p.ClosesAt, now),
+			Options:  []PollOption{},
+		}
+		if voted {
+			resp.MyVote = &myVote
+		}
+
+		total := int32(0)
+		for _, option := range options[p.ChirpID] {
+			o := PollOption{
+				Position: option.Position,
+				Text:     option.Text,
+			}
+			if showResults {
+				count := option.VoteCount
+				o.VoteCount = &count
+			}
+			total += option.VoteCount
+			resp.Options = append(resp.Options, o)
+		}
+		if showResults {
+			resp.VoteCount = &total
+		}
+		polls[p.ChirpID] = resp
+	}
+	return polls, nil
+}
+
+// handlerPollsVote casts the authenticated user's vote in a chirp's poll.
+// Each user votes once: voting again for the same option hands back the
+// chirp, while voting for a different one is rejected. Voting through a
+// rechirp votes in its original.
+func (cfg *apiConfig) handlerPollsVote(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Position *int32 `json:"position"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+	if params.Position == nil {
+		err := errors.New("position is required")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	chirp, err := getViewableChirp(r.Context(), cfg.db, originalChirpID(target), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+
+	_, err = cfg.db.GetPoll(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find poll", err)
+		return
+	}
+	dbOptions, err := cfg.db.GetPollOptionsByChirpIDs(r.Context(), []uuid.UUID{chirp.ID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get poll", err)
+		return
+	}
+	if *params.Position < 0 || int(*params.Position) >= len(dbOptions) {
+		err := fmt.Errorf("position must be between 0 and %d", len(dbOptions)-1)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	status := http.StatusCreated
+	n, err := cfg.db.VotePoll(r.Context(), database.VotePollParams{
+		UserID:   userID,
+		Position: *params.Position,
+		ChirpID:  chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't vote", err)
+		return
+	}
+	if n == 0 {
+		vote, err := cfg.db.GetPollVote(r.Context(), database.GetPollVoteParams{
+			ChirpID: chirp.ID,
+			UserID:  userID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			err := errors.New("poll has closed")
+			respondWithError(w, http.StatusConflict, err.Error(), err)
+			return
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get vote", err)
+			return
+		case vote.Position != *params.Position:
+			err := errors.New("you've already voted in this poll")
+			respondWithError(w, http.StatusConflict, err.Error(), err)
+			return
+		}
+		status = http.StatusOK
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, status, resp)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerChirpsRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	err = accountRestriction(user)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	originalID := originalChirpID(target)
+
+	// Rechirps go out to everyone, so only chirps everyone can already see
+	// may be rechirped.
+	original, err := getViewableChirp(r.Context(), cfg.db, originalID, userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	public, err := isPublicChirp(r.Context(), cfg.db, original)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+	if !public {
+		err := errors.New("only public chirps can be rechirped")
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	status := http.StatusCreated
+	rechirp, err := qtx.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:      userID,
+		RechirpOfID: originalID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already rechirped: hand back the existing rechirp.
+		status = http.StatusOK
+		rechirp, err = qtx.GetRechirp(r.Context(), database.GetRechirpParams{
+			UserID:      userID,
+			RechirpOfID: originalID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating rechirp", err)
+		return
+	}
+
+	if status == http.StatusCreated {
+		err = updateShareCounts(r.Context(), qtx, rechirp, 1)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating rechirp count", err)
+			return
+		}
+
+		err = notifyChirpEvent(r.Context(), qtx, eventChirpCreated, rechirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating rechirp", err)
+			return
+		}
+
+		err = enqueueChirpCreated(r.Context(), qtx, rechirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating rechirp", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating rechirp", err)
+		return
+	}
+
+	chirp, err := cfg.chirpResponse(r.Context(), rechirp, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error building chirp response", err)
+		return
+	}
+
+	respondWithJSON(w, status, chirp)
+}
+
+func (cfg *apiConfig) handlerChirpsUndoRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// The original may already be gone, so the rechirp is looked up by the
+	// ID it points at rather than by loading the original.
+	rechirp, err := qtx.GetRechirp(r.Context(), database.GetRechirpParams{
+		UserID:      userID,
+		RechirpOfID: chirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find rechirp", err)
+		return
+	}
+
+	err = qtx.DeleteChirp(r.Context(), rechirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting rechirp", err)
+		return
+	}
+
+	err = updateShareCounts(r.Context(), qtx, rechirp, -1)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating rechirp count", err)
+		return
+	}
+
+	err = notifyChirpEvent(r.Context(), qtx, eventChirpDeleted, rechirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting rechirp", err)
+		return
+	}
+
+	err = enqueueChirpDeleted(r.Context(), qtx, rechirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting rechirp", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting rechirp", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
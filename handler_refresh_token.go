@@ -2,7 +2,6 @@ package main
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/chonginator/chirpy/internal/auth"
 )
@@ -24,7 +23,13 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Hour)
+	err = accountRestriction(user)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, err.Error(), err)
+		return
+	}
+
+	accessToken, err := cfg.makeAccessToken(user)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error making access token", err)
 		return
@@ -42,7 +47,32 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = cfg.db.RevokeRefreshToken(r.Context(), refreshToken)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	token, err := qtx.RevokeRefreshToken(r.Context(), refreshToken)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    token.UserID,
+		Action:     auditTokenRevoked,
+		TargetType: auditTargetUser,
+		TargetID:   token.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
 		return
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxReportDetailsLength = 1000
+
+var reportReasons = []string{
+	"spam",
+	"harassment",
+	"hate",
+	"violence",
+	"self_harm",
+	"misinformation",
+	"other",
+}
+
+type Report struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details"`
+}
+
+func reportResponse(r database.Report) Report {
+	return Report{
+		ID:         r.ID,
+		CreatedAt:  r.CreatedAt,
+		ChirpID:    r.ChirpID,
+		ReporterID: r.ReporterID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+	}
+}
+
+// handlerChirpsReport reports a chirp to the moderators. Each user can
+// report a chirp once; reporting it again returns the original report.
+// Reporting a rechirp reports its original.
+func (cfg *apiConfig) handlerChirpsReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	if !slices.Contains(reportReasons, params.Reason) {
+		err := fmt.Errorf("reason must be one of %v", reportReasons)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if chirptext.Length(params.Details) > maxReportDetailsLength {
+		err := fmt.Errorf("details exceed %d characters", maxReportDetailsLength)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	target, err := cfg.db.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+		return
+	}
+	chirpID = originalChirpID(target)
+	if chirpID != target.ID {
+		target, err = cfg.db.GetChirpByID(r.Context(), chirpID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Couldn't find chirp", err)
+			return
+		}
+	}
+
+	viewable, err := canViewChirp(r.Context(), cfg.db, target, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+	if !viewable {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp", sql.ErrNoRows)
+		return
+	}
+	if target.UserID == userID {
+		err := errors.New("you can't report your own chirp")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	existing, err := cfg.db.GetReportByReporter(r.Context(), database.GetReportByReporterParams{
+		ChirpID:    chirpID,
+		ReporterID: userID,
+	})
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, reportResponse(existing))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get report", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	modCase, err := qtx.OpenModerationCase(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't open moderation case", err)
+		return
+	}
+
+	report, err := qtx.CreateReport(r.Context(), database.CreateReportParams{
+		CaseID:     modCase.ID,
+		ChirpID:    chirpID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// A concurrent request from the same user got there first.
+		tx.Rollback()
+		existing, err := cfg.db.GetReportByReporter(r.Context(), database.GetReportByReporterParams{
+			ChirpID:    chirpID,
+			ReporterID: userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get report", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, reportResponse(existing))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create report", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create report", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reportResponse(report))
+}
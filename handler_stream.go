@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/stream"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	chirpEventsChannel       = "chirp_events"
+	userNotificationsChannel = "user_notifications"
+
+	// chirpStreamReplaySize is how many recent events a reconnecting client
+	// can catch up on with Last-Event-ID.
+	chirpStreamReplaySize = 1000
+	// streamHeartbeatInterval keeps idle connections from being closed by
+	// proxies along the way.
+	streamHeartbeatInterval = 15 * time.Second
+)
+
+// notifyChirpEvent announces a chirp event to every replica. NOTIFY is
+// transactional, so when q is bound to a transaction the event is only
+// sent if it commits. IDs come from a sequence so they mean the same thing
+// on every replica.
+func notifyChirpEvent(ctx context.Context, q *database.Queries, eventType string, chirp database.Chirp) error {
+	err := q.NotifyChirpEvent(ctx, database.NotifyChirpEventParams{
+		Type:    eventType,
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't notify chirp event: %w", err)
+	}
+	return nil
+}
+
+// listenEvents relays chirp events and user notifications from Postgres
+// into the local brokers. Events sent while the listener is reconnecting
+// are lost; clients that need every chirp can fall back to GET /api/chirps.
+func (cfg *apiConfig) listenEvents(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	for _, channel := range []string{chirpEventsChannel, userNotificationsChannel, outboxChannel} {
+		err := listener.Listen(channel)
+		if err != nil {
+			log.Printf("Error listening on %s: %v", channel, err)
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established.
+			if n == nil {
+				continue
+			}
+			switch n.Channel {
+			case chirpEventsChannel:
+				event, err := cfg.chirpStreamEvent(ctx, n.Extra)
+				if err != nil {
+					log.Printf("Error handling chirp event: %v", err)
+					continue
+				}
+				cfg.chirpStream.Publish(event)
+			case userNotificationsChannel:
+				event, err := userNotificationEvent(n.Extra)
+				if err != nil {
+					log.Printf("Error handling user notification: %v", err)
+					continue
+				}
+				cfg.notificationStream.Publish(event)
+			case outboxChannel:
+				cfg.wakeOutbox()
+			}
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// userNotificationEvent passes a notification's payload through to the
+// recipient as is.
+func userNotificationEvent(payload string) (stream.Event, error) {
+	type notification struct {
+		Type   string    `json:"type"`
+		UserID uuid.UUID `json:"user_id"`
+	}
+
+	n := notification{}
+	err := json.Unmarshal([]byte(payload), &n)
+	if err != nil {
+		return stream.Event{}, err
+	}
+	return stream.Event{
+		Type:   n.Type,
+		UserID: n.UserID,
+		Data:   []byte(payload),
+	}, nil
+}
+
+// chirpStreamEvent turns a notification payload into the event sent to
+// clients: the full chirp for a new one, just its ID for a deletion. The
+// authors of any chirps a new one shares go with it, so subscribers can
+// leave out shares of users they've blocked or muted.
+func (cfg *apiConfig) chirpStreamEvent(ctx context.Context, payload string) (stream.Event, error) {
+	type notification struct {
+		ID      int64     `json:"id"`
+		Type    string    `json:"type"`
+		ChirpID uuid.UUID `json:"chirp_id"`
+		UserID  uuid.UUID `json:"user_id"`
+	}
+	type deleted struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	n := notification{}
+	err := json.Unmarshal([]byte(payload), &n)
+	if err != nil {
+		return stream.Event{}, err
+	}
+
+	var data []byte
+	var embeddedUserIDs []uuid.UUID
+	switch n.Type {
+	case eventChirpCreated:
+		dbChirp, err := cfg.db.GetChirpByID(ctx, n.ChirpID)
+		if err != nil {
+			return stream.Event{}, fmt.Errorf("couldn't get chirp %s: %w", n.ChirpID, err)
+		}
+		chirp, err := cfg.chirpResponse(ctx, dbChirp, uuid.Nil)
+		if err != nil {
+			return stream.Event{}, err
+		}
+		for _, embed := range []*ChirpEmbed{chirp.RechirpOf, chirp.QuotedChirp} {
+			if embed != nil && embed.Chirp != nil {
+				embeddedUserIDs = append(embeddedUserIDs, embed.Chirp.UserID)
+			}
+		}
+		data, err = json.Marshal(chirp)
+		if err != nil {
+			return stream.Event{}, err
+		}
+	case eventChirpDeleted:
+		data, err = json.Marshal(deleted{ID: n.ChirpID})
+		if err != nil {
+			return stream.Event{}, err
+		}
+	default:
+		return stream.Event{}, fmt.Errorf("unknown chirp event type %q", n.Type)
+	}
+
+	return stream.Event{
+		ID:              strconv.FormatInt(n.ID, 10),
+		Type:            n.Type,
+		UserID:          n.UserID,
+		EmbeddedUserIDs: embeddedUserIDs,
+		Data:            data,
+	}, nil
+}
+
+// handlerChirpsStream sends new and deleted chirps as Server-Sent Events,
+// optionally only those by author_id. A client reconnecting with
+// Last-Event-ID first gets what it missed from the replay buffer.
+func (cfg *apiConfig) handlerChirpsStream(w http.ResponseWriter, r *http.Request) {
+	authorID := uuid.Nil
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		var err error
+		authorID, err = uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// Clear any server write timeout so it doesn't cut the stream off.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	sub, replay := cfg.chirpStream.Subscribe(r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e stream.Event) error {
+		if authorID != uuid.Nil && e.UserID != authorID {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+		return err
+	}
+
+	for _, e := range replay {
+		if send(e) != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			// A closed channel means this client fell too far behind. It
+			// can reconnect and resume from the last event it saw.
+			if !ok {
+				return
+			}
+			if send(e) != nil {
+				return
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
@@ -1,28 +1,39 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/chirptext"
 	"github.com/chonginator/chirpy/internal/database"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// errUsernameTaken is returned when a username is already in use.
+var errUsernameTaken = errors.New("username is already taken")
+
 type User struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Email       string    `json:"email"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Username    string    `json:"username,omitempty"`
+	Protected   bool      `json:"protected"`
 }
 
 func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -45,16 +56,52 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	username, err := parseUsername(params.Username)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
 		return
 	}
 
-	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Username:       username,
+	})
+	if isUniqueViolation(err, "users_username_key") {
+		respondWithError(w, http.StatusConflict, errUsernameTaken.Error(), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating user", err)
+		return
+	}
+
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    user.ID,
+		Action:     auditUserCreated,
+		TargetType: auditTargetUser,
+		TargetID:   user.ID,
 	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating user", err)
 		return
@@ -66,6 +113,27 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
 		IsChirpyRed: user.IsChirpyRed,
+		Username:    user.Username.String,
+		Protected:   user.Protected,
 	},
 	)
 }
+
+// parseUsername validates an optional username. Usernames are stored in
+// lowercase so that mentions resolve regardless of case.
+func parseUsername(username string) (sql.NullString, error) {
+	if username == "" {
+		return sql.NullString{}, nil
+	}
+	if !chirptext.ValidUsername(username) {
+		return sql.NullString{}, errors.New("username must be 1-30 letters, digits or underscores")
+	}
+	return sql.NullString{String: strings.ToLower(username), Valid: true}, nil
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a write
+// that would break the named unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerUsersDelete(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find access token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	// Deleting an account can't be undone once the grace period is over, so
+	// a stolen access token alone must not be enough to request it.
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err = qtx.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID: user.ID,
+		DeleteAfter: sql.NullTime{
+			Time:  time.Now().Add(cfg.deletionGracePeriod).UTC(),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule account deletion", err)
+		return
+	}
+
+	// Signing out everywhere means the only way back in is to log in with
+	// the password, which cancels the pending deletion.
+	err = qtx.RevokeAllRefreshTokensForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	err = recordAudit(r, qtx, auditRecord{
+		ActorID:    user.ID,
+		Action:     auditDeletionRequest,
+		TargetType: auditTargetUser,
+		TargetID:   user.ID,
+		Metadata:   map[string]any{"delete_after": user.DeleteAfter.Time},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule account deletion", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{
+		DeleteAfter: user.DeleteAfter.Time,
+	})
+}
+
+// purgeDeletedUsers hard-deletes accounts whose deletion grace period has
+// run out. Chirps and refresh tokens go with them through ON DELETE CASCADE.
+func (cfg *apiConfig) purgeDeletedUsers(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.purgeUsersPastGracePeriod(ctx)
+		if err != nil {
+			log.Printf("Error purging deleted users: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d deleted users", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeUsersPastGracePeriod deletes the expired accounts in one transaction
+// with the counter updates their cascading likes, rechirps, quotes and
+// poll votes would otherwise leave stale.
+func (cfg *apiConfig) purgeUsersPastGracePeriod(ctx context.Context) (int64, error) {
+	tx, err := cfg.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = qtx.RemoveLikesOfUsersPastGracePeriod(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = qtx.RemoveSharesOfUsersPastGracePeriod(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = qtx.RemovePollVotesOfUsersPastGracePeriod(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := qtx.DeleteUsersPastGracePeriod(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
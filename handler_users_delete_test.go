@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// testDB connects to the migrated database in TEST_DB_URL, skipping the
+// test if there isn't one. The tests leave their rows behind, so don't
+// point it at a database you care about.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL must be set to a migrated database")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("couldn't open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPurgeUsersPastGracePeriodPollVotes(t *testing.T) {
+	ctx := context.Background()
+	dbConn := testDB(t)
+	cfg := &apiConfig{db: database.New(dbConn), dbConn: dbConn}
+
+	createUser := func(deleteAfter time.Duration) database.User {
+		t.Helper()
+		user, err := cfg.db.CreateUser(ctx, database.CreateUserParams{
+			Email:          uuid.NewString() + "@example.com",
+			HashedPassword: "unused",
+		})
+		if err != nil {
+			t.Fatalf("couldn't create user: %v", err)
+		}
+		if deleteAfter != 0 {
+			user, err = cfg.db.ScheduleUserDeletion(ctx, database.ScheduleUserDeletionParams{
+				ID:          user.ID,
+				DeleteAfter: sql.NullTime{Time: time.Now().Add(deleteAfter).UTC(), Valid: true},
+			})
+			if err != nil {
+				t.Fatalf("couldn't schedule user deletion: %v", err)
+			}
+		}
+		return user
+	}
+
+	author := createUser(0)
+	chirp, err := cfg.db.CreateChirp(ctx, database.CreateChirpParams{
+		Body:       "Cats or dogs?",
+		UserID:     author.ID,
+		Visibility: "public",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create chirp: %v", err)
+	}
+	_, err = cfg.db.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirp.ID,
+		ClosesAt: time.Now().Add(time.Hour).UTC(),
+	})
+	if err != nil {
+		t.Fatalf("couldn't create poll: %v", err)
+	}
+	for i, text := range []string{"Cats", "Dogs"} {
+		err = cfg.db.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirp.ID,
+			Position: int32(i),
+			Text:     text,
+		})
+		if err != nil {
+			t.Fatalf("couldn't create poll option: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		deleteAfter time.Duration
+		position    int32
+		counted     bool
+	}{
+		{
+			name:     "Voter keeping their account",
+			position: 0,
+			counted:  true,
+		},
+		{
+			name:        "Voter still in their grace period",
+			deleteAfter: time.Hour,
+			position:    0,
+			counted:     true,
+		},
+		{
+			name:        "Voter past their grace period",
+			deleteAfter: -time.Hour,
+			position:    1,
+		},
+		{
+			name:        "Another voter past their grace period",
+			deleteAfter: -time.Minute,
+			position:    0,
+		},
+	}
+
+	want := map[int32]int32{}
+	for i, tc := range tests {
+		voter := createUser(tc.deleteAfter)
+		n, err := cfg.db.VotePoll(ctx, database.VotePollParams{
+			UserID:   voter.ID,
+			Position: tc.position,
+			ChirpID:  chirp.ID,
+		})
+		if err != nil || n != 1 {
+			t.Fatalf("Test %v - '%s': FAIL: couldn't vote: %v", i, tc.name, err)
+		}
+		if tc.counted {
+			want[tc.position]++
+		}
+	}
+
+	_, err = cfg.purgeUsersPastGracePeriod(ctx)
+	if err != nil {
+		t.Fatalf("couldn't purge users: %v", err)
+	}
+
+	options, err := cfg.db.GetPollOptionsByChirpIDs(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		t.Fatalf("couldn't get poll options: %v", err)
+	}
+	for _, option := range options {
+		if option.VoteCount != want[option.Position] {
+			t.Errorf("FAIL: expected %d votes for %q, got %d", want[option.Position], option.Text, option.VoteCount)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) handlerUsersExport(w http.ResponseWriter, r *http.Request) {
+	type profile struct {
+		User
+		DeleteAfter *time.Time `json:"delete_after"`
+	}
+	type session struct {
+		CreatedAt time.Time  `json:"created_at"`
+		ExpiresAt time.Time  `json:"expires_at"`
+		RevokedAt *time.Time `json:"revoked_at"`
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find access token", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	dbChirps, err := cfg.db.GetChirpsByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirps", err)
+		return
+	}
+
+	refreshTokens, err := cfg.db.GetRefreshTokensByUserID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get sessions", err)
+		return
+	}
+
+	userProfile := profile{
+		User: User{
+			ID:          user.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			Email:       user.Email,
+			IsChirpyRed: user.IsChirpyRed,
+			Username:    user.Username.String,
+			Protected:   user.Protected,
+		},
+	}
+	if user.DeleteAfter.Valid {
+		userProfile.DeleteAfter = &user.DeleteAfter.Time
+	}
+
+	chirps, err := cfg.chirpsResponse(r.Context(), dbChirps, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't build chirps", err)
+		return
+	}
+
+	// The token values are live credentials, so sessions are exported
+	// without them.
+	sessions := []session{}
+	for _, token := range refreshTokens {
+		s := session{
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		}
+		if token.RevokedAt.Valid {
+			s.RevokedAt = &token.RevokedAt.Time
+		}
+		sessions = append(sessions, s)
+	}
+
+	archive, err := buildExportArchive(map[string]interface{}{
+		"profile.json":  userProfile,
+		"chirps.json":   chirps,
+		"sessions.json": sessions,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't build export archive", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, userID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// buildExportArchive writes each value as an indented JSON file into a ZIP
+// archive. The archive is built in memory so that a failure can still be
+// reported with a proper error response.
+func buildExportArchive(files map[string]interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	for name, payload := range files {
+		data, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal %s: %w", name, err)
+		}
+
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
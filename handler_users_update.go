@@ -12,6 +12,7 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	accessToken, err := auth.GetBearerToken(r.Header)
@@ -34,17 +35,76 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	username, err := parseUsername(params.Username)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	oldUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password", err)
 		return
 	}
 
-	user, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.UpdateUser(r.Context(), database.UpdateUserParams{
 		ID:             userID,
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Username:       username,
 	})
+	if isUniqueViolation(err, "users_username_key") {
+		respondWithError(w, http.StatusConflict, errUsernameTaken.Error(), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
+		return
+	}
+
+	if user.Email != oldUser.Email {
+		err = recordAudit(r, qtx, auditRecord{
+			ActorID:    user.ID,
+			Action:     auditEmailChanged,
+			TargetType: auditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"old_email": oldUser.Email, "new_email": user.Email},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+			return
+		}
+	}
+	// Every update sets the password, so it only counts as a change if the
+	// old hash doesn't match it.
+	if auth.CheckPasswordHash(params.Password, oldUser.HashedPassword) != nil {
+		err = recordAudit(r, qtx, auditRecord{
+			ActorID:    user.ID,
+			Action:     auditPasswordChanged,
+			TargetType: auditTargetUser,
+			TargetID:   user.ID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
 		return
@@ -56,5 +116,7 @@ func (cfg *apiConfig) handlerUsersUpdate(w http.ResponseWriter, r *http.Request)
 		UpdatedAt:   user.UpdatedAt,
 		Email:       user.Email,
 		IsChirpyRed: user.IsChirpyRed,
+		Username:    user.Username.String,
+		Protected:   user.Protected,
 	})
 }
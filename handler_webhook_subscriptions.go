@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/events"
+	"github.com/chonginator/chirpy/internal/webhook"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookDeliveryPending   = "pending"
+	webhookDeliverySucceeded = "succeeded"
+	webhookDeliveryFailed    = "failed"
+
+	// maxWebhookAttempts is how many times a delivery is tried before it's
+	// given up on. With webhook.Backoff the last attempt comes a little over
+	// four hours after the first.
+	maxWebhookAttempts = 10
+	// maxWebhookFailures is how many attempts in a row can fail across a
+	// subscription's deliveries before it's disabled.
+	maxWebhookFailures = 20
+
+	webhookDeliveryBatchSize = 20
+	webhookDeliveryTimeout   = 10 * time.Second
+	// webhookDeliveryLease must outlast sending a whole batch, or another
+	// worker could claim the same deliveries.
+	webhookDeliveryLease   = 5 * time.Minute
+	webhookDeliveriesLimit = 50
+)
+
+// webhookClient sends webhooks. It won't connect to internal addresses or
+// follow redirects, since subscribers choose where requests go.
+var webhookClient = webhook.NewClient()
+
+// webhookEventTypes are the events integrations can subscribe to.
+var webhookEventTypes = []string{
+	eventChirpCreated,
+	eventChirpDeleted,
+	eventUserUpgraded,
+}
+
+type WebhookSubscription struct {
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	UserID              *uuid.UUID `json:"user_id,omitempty"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	// Secret is only returned when the subscription is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID            uuid.UUID                `json:"id"`
+	CreatedAt     time.Time                `json:"created_at"`
+	EventType     string                   `json:"event_type"`
+	Status        string                   `json:"status"`
+	Attempts      int32                    `json:"attempts"`
+	NextAttemptAt *time.Time               `json:"next_attempt_at"`
+	DeliveredAt   *time.Time               `json:"delivered_at"`
+	History       []WebhookDeliveryAttempt `json:"history"`
+}
+
+type WebhookDeliveryAttempt struct {
+	CreatedAt  time.Time `json:"created_at"`
+	StatusCode *int32    `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int32     `json:"duration_ms"`
+}
+
+// webhookPayload is the body of every webhook request. ID stays the same
+// across retries and redeliveries so receivers can drop duplicates.
+type webhookPayload struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func webhookSubscriptionResponse(s database.WebhookSubscription) WebhookSubscription {
+	subscription := WebhookSubscription{
+		ID:                  s.ID,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+		URL:                 s.Url,
+		Events:              s.EventTypes,
+		Enabled:             s.Enabled,
+		ConsecutiveFailures: s.ConsecutiveFailures,
+	}
+	if s.UserID.Valid {
+		subscription.UserID = &s.UserID.UUID
+	}
+	if s.DisabledAt.Valid {
+		subscription.DisabledAt = &s.DisabledAt.Time
+	}
+	return subscription
+}
+
+func webhookDeliveryResponse(d database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        d.ID,
+		CreatedAt: d.CreatedAt,
+		EventType: d.EventType,
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		History:   []WebhookDeliveryAttempt{},
+	}
+	if d.Status == webhookDeliveryPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return delivery
+}
+
+type webhookScopeKey struct{}
+
+// adminWebhooks serves a webhook handler for admin subscriptions, which
+// receive every event rather than only those about one user's account.
+func adminWebhooks(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), webhookScopeKey{}, true)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// webhookOwner returns whose subscriptions the request manages: the
+// authenticated user's, or the admin ones under adminWebhooks. It responds
+// with an error if there's no valid JWT.
+func (cfg *apiConfig) webhookOwner(w http.ResponseWriter, r *http.Request) (uuid.NullUUID, bool) {
+	if admin, _ := r.Context().Value(webhookScopeKey{}).(bool); admin {
+		return uuid.NullUUID{}, true
+	}
+
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return uuid.NullUUID{}, false
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}, true
+}
+
+type webhookParameters struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+func (params webhookParameters) validate() error {
+	u, err := url.Parse(params.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	err = webhook.CheckURL(u)
+	if err != nil {
+		return err
+	}
+	if len(params.Events) == 0 {
+		return errors.New("events must not be empty")
+	}
+	for _, eventType := range params.Events {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerWebhooksCreate(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := webhookParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	err = params.validate()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create webhook secret", err)
+		return
+	}
+
+	subscription, err := cfg.db.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID:     ownerID,
+		Url:        params.URL,
+		Secret:     secret,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(params.Events))),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating webhook", err)
+		return
+	}
+
+	resp := webhookSubscriptionResponse(subscription)
+	resp.Secret = subscription.Secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) handlerWebhooksList(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	dbSubscriptions, err := cfg.db.GetWebhookSubscriptions(r.Context(), ownerID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhooks", err)
+		return
+	}
+
+	subscriptions := []WebhookSubscription{}
+	for _, subscription := range dbSubscriptions {
+		subscriptions = append(subscriptions, webhookSubscriptionResponse(subscription))
+	}
+
+	respondWithJSON(w, http.StatusOK, subscriptions)
+}
+
+// webhookSubscription loads the subscription named in the path if it
+// belongs to ownerID, responding with an error if it doesn't.
+func (cfg *apiConfig) webhookSubscription(w http.ResponseWriter, r *http.Request, ownerID uuid.NullUUID) (database.WebhookSubscription, bool) {
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID", err)
+		return database.WebhookSubscription{}, false
+	}
+
+	subscription, err := cfg.db.GetWebhookSubscription(r.Context(), database.GetWebhookSubscriptionParams{
+		ID:      webhookID,
+		OwnerID: ownerID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find webhook", err)
+		return database.WebhookSubscription{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook", err)
+		return database.WebhookSubscription{}, false
+	}
+	return subscription, true
+}
+
+func (cfg *apiConfig) handlerWebhooksGet(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, ok := cfg.webhookSubscription(w, r, ownerID)
+	if !ok {
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, webhookSubscriptionResponse(subscription))
+}
+
+// handlerWebhooksUpdate replaces a subscription's URL and events. Setting
+// enabled re-enables a subscription that was disabled for failing, and
+// clears its failure count.
+func (cfg *apiConfig) handlerWebhooksUpdate(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, ok := cfg.webhookSubscription(w, r, ownerID)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := webhookParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Error decoding parameters", err)
+		return
+	}
+
+	err = params.validate()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	enabled := subscription.Enabled
+	if params.Enabled != nil {
+		enabled = *params.Enabled
+	}
+
+	subscription, err = cfg.db.UpdateWebhookSubscription(r.Context(), database.UpdateWebhookSubscriptionParams{
+		Url:        params.URL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(params.Events))),
+		Enabled:    enabled,
+		ID:         subscription.ID,
+		OwnerID:    ownerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating webhook", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, webhookSubscriptionResponse(subscription))
+}
+
+func (cfg *apiConfig) handlerWebhooksDelete(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid webhook ID", err)
+		return
+	}
+
+	n, err := cfg.db.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:      webhookID,
+		OwnerID: ownerID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error deleting webhook", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find webhook", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerWebhookDeliveriesList returns a subscription's most recent
+// deliveries, each with every attempt made to send it.
+func (cfg *apiConfig) handlerWebhookDeliveriesList(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, ok := cfg.webhookSubscription(w, r, ownerID)
+	if !ok {
+		return
+	}
+
+	dbDeliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          webhookDeliveriesLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook deliveries", err)
+		return
+	}
+
+	deliveryIDs := make([]uuid.UUID, 0, len(dbDeliveries))
+	for _, delivery := range dbDeliveries {
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	dbAttempts, err := cfg.db.GetWebhookDeliveryAttempts(r.Context(), deliveryIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get webhook delivery attempts", err)
+		return
+	}
+
+	history := map[uuid.UUID][]WebhookDeliveryAttempt{}
+	for _, a := range dbAttempts {
+		attempt := WebhookDeliveryAttempt{
+			CreatedAt:  a.CreatedAt,
+			Error:      a.Error.String,
+			DurationMs: a.DurationMs,
+		}
+		if a.StatusCode.Valid {
+			attempt.StatusCode = &a.StatusCode.Int32
+		}
+		history[a.DeliveryID] = append(history[a.DeliveryID], attempt)
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, d := range dbDeliveries {
+		delivery := webhookDeliveryResponse(d)
+		if attempts, ok := history[d.ID]; ok {
+			delivery.History = attempts
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// handlerWebhookDeliveriesRedeliver queues a delivery to be sent again
+// straight away, with a fresh set of retries, whatever its status. A
+// delivery a worker is sending right now can't be redelivered until the
+// attempt is recorded.
+func (cfg *apiConfig) handlerWebhookDeliveriesRedeliver(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := cfg.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	subscription, ok := cfg.webhookSubscription(w, r, ownerID)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid delivery ID", err)
+		return
+	}
+
+	if !subscription.Enabled {
+		err := errors.New("webhook is disabled")
+		respondWithError(w, http.StatusConflict, err.Error(), err)
+		return
+	}
+
+	delivery, err := cfg.db.RedeliverWebhookDelivery(r.Context(), database.RedeliverWebhookDeliveryParams{
+		ID:             deliveryID,
+		SubscriptionID: subscription.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = cfg.db.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+			ID:             deliveryID,
+			SubscriptionID: subscription.ID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			respondWithError(w, http.StatusNotFound, "Couldn't find delivery", err)
+		case err != nil:
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get delivery", err)
+		default:
+			err := errors.New("delivery is being sent, try again shortly")
+			respondWithError(w, http.StatusConflict, err.Error(), err)
+		}
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error redelivering webhook", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, webhookDeliveryResponse(delivery))
+}
+
+// subscribeWebhooks queues a delivery to every enabled subscription that
+// wants each event.
+func (cfg *apiConfig) subscribeWebhooks(bus *events.Bus) {
+	for _, eventType := range webhookEventTypes {
+		bus.Subscribe(eventType, cfg.enqueueWebhookDeliveries)
+	}
+}
+
+// enqueueWebhookDeliveries relies on every webhook event naming the user
+// it's about in user_id.
+func (cfg *apiConfig) enqueueWebhookDeliveries(ctx context.Context, e events.Event) error {
+	subject := struct {
+		UserID uuid.UUID `json:"user_id"`
+	}{}
+	err := e.Decode(&subject)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventType: e.Type,
+		Payload:   e.Payload,
+		UserID:    subject.UserID,
+		EventKey:  e.Key,
+	})
+	return err
+}
+
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := cfg.deliverDueWebhooks(ctx)
+		if err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		} else if n > 0 {
+			log.Printf("Attempted %d webhook deliveries", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDueWebhooks sends one batch of deliveries that are due. Claimed
+// deliveries are leased rather than locked, so a slow receiver doesn't hold
+// a transaction open and replicas never send the same delivery at once.
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context) (int, error) {
+	deliveries, err := cfg.db.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil: time.Now().Add(webhookDeliveryLease).UTC(),
+		MaxResults: webhookDeliveryBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		subscription, err := cfg.db.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		err = cfg.deliverWebhook(ctx, subscription, delivery)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliverWebhook makes one attempt at a delivery and records the outcome.
+// An error means the outcome couldn't be recorded, not that the receiver
+// failed.
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, subscription database.WebhookSubscription, delivery database.WebhookDelivery) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	statusCode, sendErr := cfg.sendWebhook(ctx, subscription, delivery.ID, body)
+
+	attempt := database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID: delivery.ID,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+	}
+	if sendErr != nil {
+		attempt.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	err = cfg.db.CreateWebhookDeliveryAttempt(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	if sendErr == nil {
+		err = cfg.db.MarkWebhookDeliverySucceeded(ctx, delivery.ID)
+		if err != nil {
+			return err
+		}
+		return cfg.db.RecordWebhookSuccess(ctx, subscription.ID)
+	}
+
+	attempts := int(delivery.Attempts) + 1
+	status := webhookDeliveryPending
+	if attempts >= maxWebhookAttempts {
+		status = webhookDeliveryFailed
+	}
+	err = cfg.db.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		ID:            delivery.ID,
+		Status:        status,
+		NextAttemptAt: time.Now().Add(webhook.Backoff(attempts)).UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	updated, err := cfg.db.RecordWebhookFailure(ctx, database.RecordWebhookFailureParams{
+		MaxFailures: maxWebhookFailures,
+		ID:          subscription.ID,
+	})
+	if err != nil {
+		return err
+	}
+	if subscription.Enabled && !updated.Enabled {
+		log.Printf("Disabled webhook %s after %d failed attempts", subscription.ID, updated.ConsecutiveFailures)
+	}
+	return nil
+}
+
+// sendWebhook posts body to the subscription's URL. Anything but a 2xx
+// response is a failure, including a redirect.
+func (cfg *apiConfig) sendWebhook(ctx context.Context, subscription database.WebhookSubscription, deliveryID uuid.UUID, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookDeliveryTimeout)
+	defer cancel()
+
+	req, err := webhook.NewRequest(ctx, subscription.Url, subscription.Secret, deliveryID.String(), body, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
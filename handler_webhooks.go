@@ -43,7 +43,25 @@ func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	_, err = cfg.db.UpgradeToChirpyRed(r.Context(), params.Data.UserID)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.GetUserByID(r.Context(), params.Data.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+		return
+	}
+
+	_, err = qtx.UpgradeToChirpyRed(r.Context(), params.Data.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
@@ -53,5 +71,31 @@ func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Polka retries webhooks, so only a real upgrade is announced.
+	if !user.IsChirpyRed {
+		err = enqueueEvent(r.Context(), qtx, eventUserUpgraded, userUpgradedEvent{UserID: user.ID})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error upgrading user", err)
+			return
+		}
+
+		err = recordAudit(r, qtx, auditRecord{
+			Action:     auditChirpyRedGranted,
+			TargetType: auditTargetUser,
+			TargetID:   user.ID,
+			Metadata:   map[string]any{"source": "polka"},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error recording audit event", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error upgrading user", err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
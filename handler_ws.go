@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/chonginator/chirpy/internal/stream"
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	wsChannelFeed          = "feed"
+	wsChannelTimeline      = "timeline"
+	wsChannelNotifications = "notifications"
+
+	wsMaxMessageBytes = 4096
+	wsPingInterval    = 30 * time.Second
+	wsWriteTimeout    = 10 * time.Second
+	// wsRelationsRefreshInterval is how often a connection reloads who its
+	// user follows, blocks and mutes and the words they've muted, so chirp
+	// events pick up the changes.
+	wsRelationsRefreshInterval = time.Minute
+)
+
+var errWSClientTooSlow = errors.New("client fell too far behind")
+
+// wsClientFrame is a message from the client, either
+// {"type": "subscribe", "channel": "feed"} or the same with "unsubscribe".
+type wsClientFrame struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+// wsServerFrame is a message to the client. Events carry the same data as
+// the equivalent Server-Sent Event; the other types acknowledge or reject
+// client frames.
+type wsServerFrame struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// handlerWebSocket upgrades to a WebSocket that multiplexes the global
+// feed, the user's timeline and their notifications. Clients subscribe to
+// channels by name once connected.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	userID, err := auth.ValidateJWT(accessToken, cfg.jwtSecret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Printf("Error accepting WebSocket: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsMaxMessageBytes)
+
+	session := &wsSession{
+		cfg:       cfg,
+		conn:      conn,
+		userID:    userID,
+		channels:  map[string]bool{},
+		following: map[uuid.UUID]bool{},
+		words:     feed.NewWordFilter(nil),
+		replies:   make(chan wsServerFrame, 16),
+	}
+	err = session.run(r.Context())
+
+	switch {
+	case errors.Is(err, errWSClientTooSlow):
+		conn.Close(websocket.StatusTryAgainLater, err.Error())
+	case websocket.CloseStatus(err) != -1:
+		// The client closed the connection.
+	default:
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+
+	mu        sync.Mutex
+	channels  map[string]bool
+	following map[uuid.UUID]bool
+	relations feed.Relations
+	words     *feed.WordFilter
+
+	// replies carries acknowledgements from the reader to the writer.
+	replies chan wsServerFrame
+}
+
+// run writes frames until the connection fails. Both brokers drop this
+// session rather than wait if it falls behind, so a slow client never holds
+// up anyone else.
+func (s *wsSession) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	chirps, _ := s.cfg.chirpStream.Subscribe("")
+	defer chirps.Close()
+	notifications, _ := s.cfg.notificationStream.Subscribe("")
+	defer notifications.Close()
+
+	go func() {
+		cancel(s.read(ctx))
+	}()
+	go func() {
+		cancel(s.ping(ctx))
+	}()
+
+	refresh := time.NewTicker(wsRelationsRefreshInterval)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case frame := <-s.replies:
+			err := s.write(ctx, frame)
+			if err != nil {
+				return err
+			}
+		case e, ok := <-chirps.C:
+			if !ok {
+				return errWSClientTooSlow
+			}
+			e, ok, err := s.filterWords(e)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			for _, channel := range []string{wsChannelFeed, wsChannelTimeline} {
+				if !s.wants(channel, e) {
+					continue
+				}
+				err := s.write(ctx, eventFrame(channel, e))
+				if err != nil {
+					return err
+				}
+			}
+		case e, ok := <-notifications.C:
+			if !ok {
+				return errWSClientTooSlow
+			}
+			if !s.wants(wsChannelNotifications, e) {
+				continue
+			}
+			err := s.write(ctx, eventFrame(wsChannelNotifications, e))
+			if err != nil {
+				return err
+			}
+		case <-refresh.C:
+			s.mu.Lock()
+			subscribed := s.channels[wsChannelFeed] || s.channels[wsChannelTimeline]
+			s.mu.Unlock()
+			if subscribed {
+				err := s.loadRelations(ctx)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func eventFrame(channel string, e stream.Event) wsServerFrame {
+	return wsServerFrame{
+		Type:    "event",
+		Channel: channel,
+		Event:   e.Type,
+		ID:      e.ID,
+		Data:    e.Data,
+	}
+}
+
+func (s *wsSession) wants(channel string, e stream.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.channels[channel] {
+		return false
+	}
+	switch channel {
+	case wsChannelFeed:
+		return !s.relations.Hides(e.UserIDs()...)
+	case wsChannelTimeline:
+		return (e.UserID == s.userID || s.following[e.UserID]) && !s.relations.Hides(e.UserIDs()...)
+	case wsChannelNotifications:
+		return e.UserID == s.userID
+	}
+	return true
+}
+
+// filterWords applies the user's muted words to a new chirp the way chirp
+// listings do: it reports false for a chirp matching a word set to hide,
+// and marks one matching a word set to collapse as filtered.
+func (s *wsSession) filterWords(e stream.Event) (stream.Event, bool, error) {
+	if e.Type != eventChirpCreated {
+		return e, true, nil
+	}
+
+	chirp := Chirp{}
+	err := json.Unmarshal(e.Data, &chirp)
+	if err != nil {
+		return stream.Event{}, false, err
+	}
+
+	s.mu.Lock()
+	chirp.Filtered = matchMutedWords(s.words, chirp)
+	s.mu.Unlock()
+	if chirp.Filtered == nil {
+		return e, true, nil
+	}
+	if chirp.Filtered.Action == feed.ActionHide {
+		return e, false, nil
+	}
+
+	e.Data, err = json.Marshal(chirp)
+	if err != nil {
+		return stream.Event{}, false, err
+	}
+	return e, true, nil
+}
+
+// write sends a frame, giving up if the client doesn't take it in time.
+func (s *wsSession) write(ctx context.Context, frame wsServerFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	return s.conn.Write(ctx, websocket.MessageText, data)
+}
+
+func (s *wsSession) ping(ctx context.Context) error {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := s.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// read handles subscribe and unsubscribe frames until the client goes
+// away. Replies go through the writer, which owns the connection's writes.
+func (s *wsSession) read(ctx context.Context) error {
+	for {
+		_, data, err := s.conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+
+		frame := wsClientFrame{}
+		var reply wsServerFrame
+		err = json.Unmarshal(data, &frame)
+		switch {
+		case err != nil:
+			reply = wsServerFrame{Type: "error", Error: "Invalid frame"}
+		case frame.Channel != wsChannelFeed && frame.Channel != wsChannelTimeline && frame.Channel != wsChannelNotifications:
+			reply = wsServerFrame{Type: "error", Channel: frame.Channel, Error: "Unknown channel"}
+		case frame.Type == "subscribe":
+			if frame.Channel == wsChannelFeed || frame.Channel == wsChannelTimeline {
+				err := s.loadRelations(ctx)
+				if err != nil {
+					return err
+				}
+			}
+			s.mu.Lock()
+			s.channels[frame.Channel] = true
+			s.mu.Unlock()
+			reply = wsServerFrame{Type: "subscribed", Channel: frame.Channel}
+		case frame.Type == "unsubscribe":
+			s.mu.Lock()
+			delete(s.channels, frame.Channel)
+			s.mu.Unlock()
+			reply = wsServerFrame{Type: "unsubscribed", Channel: frame.Channel}
+		default:
+			reply = wsServerFrame{Type: "error", Channel: frame.Channel, Error: "Unknown frame type"}
+		}
+
+		select {
+		case s.replies <- reply:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// loadRelations reloads who the user follows, for the timeline, and who
+// they've blocked or muted and the words they've muted, which both chirp
+// channels apply.
+func (s *wsSession) loadRelations(ctx context.Context) error {
+	followeeIDs, err := s.cfg.db.GetFolloweeIDs(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+	relations, err := userRelations(ctx, s.cfg.db, s.userID)
+	if err != nil {
+		return err
+	}
+	words, err := userWordFilter(ctx, s.cfg.db, s.userID)
+	if err != nil {
+		return err
+	}
+
+	following := map[uuid.UUID]bool{}
+	for _, id := range followeeIDs {
+		following[id] = true
+	}
+
+	s.mu.Lock()
+	s.following = following
+	s.relations = relations
+	s.words = words
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func testChain(n int) []Entry {
+	entries := []Entry{}
+	var prev *Entry
+	for i := 0; i < n; i++ {
+		e := Chain(Entry{
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, i, 123456789, time.UTC),
+			ActorID:   "actor",
+			Action:    "auth.login",
+			IP:        "127.0.0.1",
+			Metadata:  []byte(`{}`),
+		}, prev)
+		entries = append(entries, e)
+		prev = &entries[len(entries)-1]
+	}
+	return entries
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(entries []Entry) []Entry
+		wantSeq int64
+	}{
+		{
+			name:    "Untouched chain",
+			tamper:  func(entries []Entry) []Entry { return entries },
+			wantSeq: 0,
+		},
+		{
+			name: "Modified field",
+			tamper: func(entries []Entry) []Entry {
+				entries[2].Action = "auth.logout"
+				return entries
+			},
+			wantSeq: 3,
+		},
+		{
+			name: "Modified and rehashed entry",
+			tamper: func(entries []Entry) []Entry {
+				entries[1].ActorID = "someone else"
+				entries[1].Hash = ComputeHash(entries[1])
+				return entries
+			},
+			wantSeq: 3,
+		},
+		{
+			name: "Removed entry",
+			tamper: func(entries []Entry) []Entry {
+				return append(entries[:1], entries[2:]...)
+			},
+			wantSeq: 3,
+		},
+		{
+			name: "Swapped entries",
+			tamper: func(entries []Entry) []Entry {
+				entries[0], entries[1] = entries[1], entries[0]
+				return entries
+			},
+			wantSeq: 2,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := tc.tamper(testChain(4))
+			seq, err := Verify(entries, 0, GenesisHash)
+			if seq != tc.wantSeq {
+				t.Errorf("Test %v - '%s': FAIL: expected first bad entry %d, got %d (%v)", i, tc.name, tc.wantSeq, seq, err)
+			}
+			if (err != nil) != (tc.wantSeq != 0) {
+				t.Errorf("Test %v - '%s': FAIL: unexpected error: %v", i, tc.name, err)
+			}
+		})
+	}
+}
+
+func TestVerifyContinuesChain(t *testing.T) {
+	entries := testChain(4)
+
+	seq, err := Verify(entries[2:], entries[1].Seq, entries[1].Hash)
+	if err != nil {
+		t.Errorf("expected the rest of the chain to verify, got entry %d: %v", seq, err)
+	}
+}
+
+func TestChainTruncatesTimestamp(t *testing.T) {
+	e := testChain(1)[0]
+	if e.CreatedAt.Nanosecond()%1000 != 0 {
+		t.Errorf("expected microsecond precision, got %v", e.CreatedAt)
+	}
+	if ComputeHash(e) != e.Hash {
+		t.Errorf("expected hash to match after truncation")
+	}
+}
@@ -90,7 +90,7 @@ func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
 	tokenSecret := "shhhhh"
 
-	validToken, err := MakeJWT(userID, tokenSecret, time.Hour)
+	validToken, err := MakeJWT(userID, []Role{RoleUser}, tokenSecret, time.Hour)
 	if err != nil {
 		t.Fatalf("Couldn't make JWT: %v", err)
 	}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Role is what a user is allowed to do. Each role can do everything the
+// ones below it can.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// roleHierarchy lists the roles from least to most privileged.
+var roleHierarchy = []Role{RoleUser, RoleModerator, RoleAdmin}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if !slices.Contains(roleHierarchy, role) {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+// Roles returns every role a user with this one holds, for the roles claim
+// of their access tokens. An unknown role holds none.
+func (r Role) Roles() []Role {
+	i := slices.Index(roleHierarchy, r)
+	if i == -1 {
+		return []Role{}
+	}
+	return slices.Clone(roleHierarchy[:i+1])
+}
+
+// AccessToken is what a valid access JWT says about its bearer.
+type AccessToken struct {
+	UserID uuid.UUID
+	Roles  []Role
+}
+
+func (t AccessToken) HasRole(role Role) bool {
+	return slices.Contains(t.Roles, role)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		role Role
+		want []Role
+	}{
+		{
+			name: "User",
+			role: RoleUser,
+			want: []Role{RoleUser},
+		},
+		{
+			name: "Moderator includes user",
+			role: RoleModerator,
+			want: []Role{RoleUser, RoleModerator},
+		},
+		{
+			name: "Admin includes everything",
+			role: RoleAdmin,
+			want: []Role{RoleUser, RoleModerator, RoleAdmin},
+		},
+		{
+			name: "Unknown role",
+			role: Role("superuser"),
+			want: []Role{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.role.Roles()
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseAccessTokenRoles(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "shhhhh"
+
+	tests := []struct {
+		name          string
+		roles         []Role
+		wantModerator bool
+		wantAdmin     bool
+	}{
+		{
+			name:  "No roles claim",
+			roles: nil,
+		},
+		{
+			name:  "User",
+			roles: RoleUser.Roles(),
+		},
+		{
+			name:          "Moderator",
+			roles:         RoleModerator.Roles(),
+			wantModerator: true,
+		},
+		{
+			name:          "Admin",
+			roles:         RoleAdmin.Roles(),
+			wantModerator: true,
+			wantAdmin:     true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString, err := MakeJWT(userID, tc.roles, tokenSecret, time.Hour)
+			if err != nil {
+				t.Fatalf("Test %v - '%s': FAIL: couldn't make JWT: %v", i, tc.name, err)
+			}
+
+			token, err := ParseAccessToken(tokenString, tokenSecret)
+			if err != nil {
+				t.Fatalf("Test %v - '%s': FAIL: unexpected error: %v", i, tc.name, err)
+			}
+			if token.UserID != userID {
+				t.Errorf("Test %v - '%s': FAIL: expected user %v, got %v", i, tc.name, userID, token.UserID)
+			}
+			if token.HasRole(RoleModerator) != tc.wantModerator {
+				t.Errorf("Test %v - '%s': FAIL: expected moderator %v, got %v", i, tc.name, tc.wantModerator, !tc.wantModerator)
+			}
+			if token.HasRole(RoleAdmin) != tc.wantAdmin {
+				t.Errorf("Test %v - '%s': FAIL: expected admin %v, got %v", i, tc.name, tc.wantAdmin, !tc.wantAdmin)
+			}
+		})
+	}
+}
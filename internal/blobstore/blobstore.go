@@ -0,0 +1,25 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore holds uploaded files by key. Keys are slash-separated paths
+// chosen by the caller; a store may reject keys it can't represent.
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing anything already
+	// there.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob
+	// isn't an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,105 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	err = store.Put(ctx, "a/b.txt", strings.NewReader("first"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	err = store.Put(ctx, "a/b.txt", strings.NewReader("second"))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	f, err := store.Open(ctx, "a/b.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("expected %q, got %q", "second", data)
+	}
+
+	err = store.Delete(ctx, "a/b.txt")
+	if err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	_, err = store.Open(ctx, "a/b.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	err = store.Delete(ctx, "a/b.txt")
+	if err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestLocalStoreKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{
+			name:    "Plain key",
+			key:     "photo.jpg",
+			wantErr: false,
+		},
+		{
+			name:    "Nested key",
+			key:     "thumbs/photo.jpg",
+			wantErr: false,
+		},
+		{
+			name:    "Empty key",
+			key:     "",
+			wantErr: true,
+		},
+		{
+			name:    "Parent directory",
+			key:     "../photo.jpg",
+			wantErr: true,
+		},
+		{
+			name:    "Escapes after cleaning",
+			key:     "thumbs/../../photo.jpg",
+			wantErr: true,
+		},
+		{
+			name:    "Absolute path",
+			key:     "/etc/passwd",
+			wantErr: true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := store.Put(context.Background(), tc.key, strings.NewReader("x"))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Test %v - '%s': FAIL: expected error: %v, got %v", i, tc.name, tc.wantErr, err)
+			}
+		})
+	}
+}
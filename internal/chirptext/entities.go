@@ -0,0 +1,143 @@
+package chirptext
+
+import (
+	"strings"
+	"unicode"
+)
+
+type EntityType string
+
+const (
+	EntityHashtag EntityType = "hashtag"
+	EntityMention EntityType = "mention"
+	EntityURL     EntityType = "url"
+)
+
+const maxUsernameLength = 30
+
+// Entity is a hashtag, mention or URL found in a chirp body. Start and End
+// are rune offsets into the body, with End exclusive. Text holds the tag
+// without its '#', the username without its '@', or the full URL.
+type Entity struct {
+	Type  EntityType
+	Text  string
+	Start int
+	End   int
+}
+
+// ParseEntities returns the entities in body in the order they appear.
+func ParseEntities(body string) []Entity {
+	runes := []rune(body)
+	entities := []Entity{}
+
+	for i := 0; i < len(runes); {
+		if i > 0 && isWordRune(runes[i-1]) {
+			i++
+			continue
+		}
+
+		if end, ok := scanURL(runes, i); ok {
+			entities = append(entities, Entity{
+				Type:  EntityURL,
+				Text:  string(runes[i:end]),
+				Start: i,
+				End:   end,
+			})
+			i = end
+			continue
+		}
+
+		switch runes[i] {
+		case '#':
+			end := i + 1
+			hasLetter := false
+			for end < len(runes) && isWordRune(runes[end]) {
+				if unicode.IsLetter(runes[end]) {
+					hasLetter = true
+				}
+				end++
+			}
+			// "#1" reads as a number rather than a topic.
+			if hasLetter {
+				entities = append(entities, Entity{
+					Type:  EntityHashtag,
+					Text:  string(runes[i+1 : end]),
+					Start: i,
+					End:   end,
+				})
+				i = end
+				continue
+			}
+		case '@':
+			end := i + 1
+			for end < len(runes) && end-i-1 < maxUsernameLength && isUsernameRune(runes[end]) {
+				end++
+			}
+			if end > i+1 && (end == len(runes) || !isWordRune(runes[end])) {
+				entities = append(entities, Entity{
+					Type:  EntityMention,
+					Text:  string(runes[i+1 : end]),
+					Start: i,
+					End:   end,
+				})
+				i = end
+				continue
+			}
+		}
+		i++
+	}
+
+	return entities
+}
+
+// ValidUsername reports whether username can be mentioned in a chirp.
+func ValidUsername(username string) bool {
+	if username == "" || len(username) > maxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if !isUsernameRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeTag returns the form a hashtag is stored and looked up under.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func scanURL(runes []rune, start int) (int, bool) {
+	rest := strings.ToLower(string(runes[start:min(start+len("https://"), len(runes))]))
+	var scheme string
+	switch {
+	case strings.HasPrefix(rest, "https://"):
+		scheme = "https://"
+	case strings.HasPrefix(rest, "http://"):
+		scheme = "http://"
+	default:
+		return 0, false
+	}
+
+	end := start + len(scheme)
+	for end < len(runes) && !unicode.IsSpace(runes[end]) {
+		end++
+	}
+	// Punctuation at the end usually belongs to the sentence, not the link.
+	for end > start+len(scheme) && strings.ContainsRune(".,:;!?'\")]", runes[end-1]) {
+		end--
+	}
+	if end == start+len(scheme) {
+		return 0, false
+	}
+	return end, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isUsernameRune(r rune) bool {
+	return r < unicode.MaxASCII && isWordRune(r)
+}
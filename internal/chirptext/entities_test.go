@@ -0,0 +1,86 @@
+package chirptext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "Plain text",
+			body: "nothing to see here",
+			want: []Entity{},
+		},
+		{
+			name: "Hashtag, mention and URL",
+			body: "#golang tips from @alice at https://go.dev/doc.",
+			want: []Entity{
+				{Type: EntityHashtag, Text: "golang", Start: 0, End: 7},
+				{Type: EntityMention, Text: "alice", Start: 18, End: 24},
+				{Type: EntityURL, Text: "https://go.dev/doc", Start: 28, End: 46},
+			},
+		},
+		{
+			name: "Offsets count runes, not bytes",
+			body: "héllo #café",
+			want: []Entity{
+				{Type: EntityHashtag, Text: "café", Start: 6, End: 11},
+			},
+		},
+		{
+			name: "Email address is not a mention",
+			body: "mail me at bob@example.com",
+			want: []Entity{},
+		},
+		{
+			name: "Numeric hashtag is ignored",
+			body: "we're #1",
+			want: []Entity{},
+		},
+		{
+			name: "Hash inside a word is ignored",
+			body: "I write C# and F#",
+			want: []Entity{},
+		},
+		{
+			name: "Username that is too long is ignored",
+			body: "@abcdefghijklmnopqrstuvwxyz12345",
+			want: []Entity{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseEntities(tc.body)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Test %v - '%s': FAIL: expected %+v, got %+v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     bool
+	}{
+		{name: "Letters, digits and underscore", username: "go_gopher42", want: true},
+		{name: "Empty", username: "", want: false},
+		{name: "Contains a dot", username: "go.gopher", want: false},
+		{name: "Non-ASCII", username: "gophér", want: false},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ValidUsername(tc.username); got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package chirptext
+
+import (
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is how many characters a URL counts as, however long it is.
+const URLWeight = 23
+
+// Length returns the length of body as a reader would count it: the number
+// of grapheme clusters after NFC normalization, so an emoji with skin tone
+// or a letter with a combining accent counts once. Each URL counts as
+// URLWeight characters.
+func Length(body string) int {
+	body = norm.NFC.String(body)
+	runes := []rune(body)
+
+	length := 0
+	last := 0
+	for _, entity := range ParseEntities(body) {
+		if entity.Type != EntityURL {
+			continue
+		}
+		length += uniseg.GraphemeClusterCount(string(runes[last:entity.Start])) + URLWeight
+		last = entity.End
+	}
+	length += uniseg.GraphemeClusterCount(string(runes[last:]))
+
+	return length
+}
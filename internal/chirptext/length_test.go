@@ -0,0 +1,58 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{
+			name: "ASCII",
+			body: "hello",
+			want: 5,
+		},
+		{
+			name: "Emoji count once each",
+			body: strings.Repeat("🐦", 50),
+			want: 50,
+		},
+		{
+			name: "Emoji with skin tone modifier",
+			body: "👍🏽",
+			want: 1,
+		},
+		{
+			name: "Family emoji joined with ZWJ",
+			body: "👨‍👩‍👧",
+			want: 1,
+		},
+		{
+			name: "Combining accent",
+			body: "café",
+			want: 4,
+		},
+		{
+			name: "Flag",
+			body: "🇳🇿",
+			want: 1,
+		},
+		{
+			name: "URL counts at a fixed weight",
+			body: "read https://example.com/a/very/long/path/that/goes/on/and/on now",
+			want: 5 + URLWeight + 4,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Length(tc.body); got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %d, got %d", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
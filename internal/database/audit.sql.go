@@ -0,0 +1,235 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: audit.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+  id,
+  created_at,
+  actor_id,
+  action,
+  target_type,
+  target_id,
+  ip,
+  user_agent,
+  request_id,
+  metadata,
+  prev_hash,
+  hash
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10,
+  $11,
+  $12
+)
+`
+
+type CreateAuditEventParams struct {
+	ID         int64
+	CreatedAt  time.Time
+	ActorID    uuid.NullUUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Ip         string
+	UserAgent  string
+	RequestID  string
+	Metadata   json.RawMessage
+	PrevHash   string
+	Hash       string
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) error {
+	_, err := q.db.ExecContext(ctx, createAuditEvent,
+		arg.ID,
+		arg.CreatedAt,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Ip,
+		arg.UserAgent,
+		arg.RequestID,
+		arg.Metadata,
+		arg.PrevHash,
+		arg.Hash,
+	)
+	return err
+}
+
+const getAuditEvents = `-- name: GetAuditEvents :many
+SELECT id, created_at, actor_id, action, target_type, target_id, ip, user_agent, request_id, metadata, prev_hash, hash FROM audit_events
+WHERE ($1::UUID IS NULL OR actor_id = $1::UUID)
+AND ($2::TEXT IS NULL OR action = $2::TEXT)
+AND ($3::TEXT IS NULL OR target_type = $3::TEXT)
+AND ($4::TEXT IS NULL OR target_id = $4::TEXT)
+AND ($5::TEXT IS NULL OR request_id = $5::TEXT)
+AND ($6::TIMESTAMP IS NULL OR created_at >= $6::TIMESTAMP)
+AND ($7::TIMESTAMP IS NULL OR created_at < $7::TIMESTAMP)
+AND ($8::BIGINT IS NULL OR id < $8::BIGINT)
+ORDER BY id DESC
+LIMIT $9
+`
+
+type GetAuditEventsParams struct {
+	ActorID    uuid.NullUUID
+	Action     sql.NullString
+	TargetType sql.NullString
+	TargetID   sql.NullString
+	RequestID  sql.NullString
+	Since      sql.NullTime
+	Until      sql.NullTime
+	Before     sql.NullInt64
+	MaxResults int32
+}
+
+func (q *Queries) GetAuditEvents(ctx context.Context, arg GetAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getAuditEvents,
+		arg.ActorID,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.RequestID,
+		arg.Since,
+		arg.Until,
+		arg.Before,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEvent
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActorID,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.Ip,
+			&i.UserAgent,
+			&i.RequestID,
+			&i.Metadata,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAuditEventsAfter = `-- name: GetAuditEventsAfter :many
+SELECT id, created_at, actor_id, action, target_type, target_id, ip, user_agent, request_id, metadata, prev_hash, hash FROM audit_events
+WHERE id > $1
+ORDER BY id ASC
+LIMIT $2
+`
+
+type GetAuditEventsAfterParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) GetAuditEventsAfter(ctx context.Context, arg GetAuditEventsAfterParams) ([]AuditEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getAuditEventsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEvent
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ActorID,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.Ip,
+			&i.UserAgent,
+			&i.RequestID,
+			&i.Metadata,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestAuditEvent = `-- name: GetLatestAuditEvent :one
+SELECT id, created_at, actor_id, action, target_type, target_id, ip, user_agent, request_id, metadata, prev_hash, hash FROM audit_events
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestAuditEvent(ctx context.Context) (AuditEvent, error) {
+	row := q.db.QueryRowContext(ctx, getLatestAuditEvent)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ActorID,
+		&i.Action,
+		&i.TargetType,
+		&i.TargetID,
+		&i.Ip,
+		&i.UserAgent,
+		&i.RequestID,
+		&i.Metadata,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const lockAuditChain = `-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_events'))
+`
+
+// Serializes writers until the transaction ends, so each new entry chains
+// onto the latest one. Nothing else touching audit_events waits on it.
+func (q *Queries) LockAuditChain(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, lockAuditChain)
+	return err
+}
@@ -0,0 +1,253 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: blocks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createBlock = `-- name: CreateBlock :one
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING blocker_id, blocked_id, created_at
+`
+
+type CreateBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) CreateBlock(ctx context.Context, arg CreateBlockParams) (Block, error) {
+	row := q.db.QueryRowContext(ctx, createBlock, arg.BlockerID, arg.BlockedID)
+	var i Block
+	err := row.Scan(&i.BlockerID, &i.BlockedID, &i.CreatedAt)
+	return i, err
+}
+
+const createMute = `-- name: CreateMute :one
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING muter_id, muted_id, created_at
+`
+
+type CreateMuteParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) CreateMute(ctx context.Context, arg CreateMuteParams) (Mute, error) {
+	row := q.db.QueryRowContext(ctx, createMute, arg.MuterID, arg.MutedID)
+	var i Mute
+	err := row.Scan(&i.MuterID, &i.MutedID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteBlock = `-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2
+`
+
+type DeleteBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) DeleteBlock(ctx context.Context, arg DeleteBlockParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBlock, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const deleteMute = `-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2
+`
+
+type DeleteMuteParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) DeleteMute(ctx context.Context, arg DeleteMuteParams) error {
+	_, err := q.db.ExecContext(ctx, deleteMute, arg.MuterID, arg.MutedID)
+	return err
+}
+
+const getBlock = `-- name: GetBlock :one
+SELECT blocker_id, blocked_id, created_at FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2
+`
+
+type GetBlockParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) GetBlock(ctx context.Context, arg GetBlockParams) (Block, error) {
+	row := q.db.QueryRowContext(ctx, getBlock, arg.BlockerID, arg.BlockedID)
+	var i Block
+	err := row.Scan(&i.BlockerID, &i.BlockedID, &i.CreatedAt)
+	return i, err
+}
+
+const getBlockedUsers = `-- name: GetBlockedUsers :many
+SELECT blocks.blocked_id AS user_id, users.username, blocks.created_at
+FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = $1
+ORDER BY blocks.created_at DESC
+`
+
+type GetBlockedUsersRow struct {
+	UserID    uuid.UUID
+	Username  sql.NullString
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]GetBlockedUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBlockedUsers, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBlockedUsersRow
+	for rows.Next() {
+		var i GetBlockedUsersRow
+		if err := rows.Scan(&i.UserID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMute = `-- name: GetMute :one
+SELECT muter_id, muted_id, created_at FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2
+`
+
+type GetMuteParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) GetMute(ctx context.Context, arg GetMuteParams) (Mute, error) {
+	row := q.db.QueryRowContext(ctx, getMute, arg.MuterID, arg.MutedID)
+	var i Mute
+	err := row.Scan(&i.MuterID, &i.MutedID, &i.CreatedAt)
+	return i, err
+}
+
+const getMutedUsers = `-- name: GetMutedUsers :many
+SELECT mutes.muted_id AS user_id, users.username, mutes.created_at
+FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = $1
+ORDER BY mutes.created_at DESC
+`
+
+type GetMutedUsersRow struct {
+	UserID    uuid.UUID
+	Username  sql.NullString
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetMutedUsers(ctx context.Context, muterID uuid.UUID) ([]GetMutedUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getMutedUsers, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMutedUsersRow
+	for rows.Next() {
+		var i GetMutedUsersRow
+		if err := rows.Scan(&i.UserID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserRelations = `-- name: GetUserRelations :many
+SELECT b.blocked_id AS user_id, 'blocking'::TEXT AS kind
+FROM blocks AS b WHERE b.blocker_id = $1
+UNION ALL
+SELECT bb.blocker_id AS user_id, 'blocked_by'::TEXT AS kind
+FROM blocks AS bb WHERE bb.blocked_id = $1
+UNION ALL
+SELECT m.muted_id AS user_id, 'muting'::TEXT AS kind
+FROM mutes AS m WHERE m.muter_id = $1
+`
+
+type GetUserRelationsRow struct {
+	UserID uuid.UUID
+	Kind   string
+}
+
+func (q *Queries) GetUserRelations(ctx context.Context, viewerID uuid.UUID) ([]GetUserRelationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserRelations, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserRelationsRow
+	for rows.Next() {
+		var i GetUserRelationsRow
+		if err := rows.Scan(&i.UserID, &i.Kind); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isBlocked = `-- name: IsBlocked :one
+SELECT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocker_id = $1 AND blocked_id = $2)
+  OR (blocker_id = $2 AND blocked_id = $1)
+)
+`
+
+type IsBlockedParams struct {
+	UserID  uuid.UUID
+	OtherID uuid.UUID
+}
+
+func (q *Queries) IsBlocked(ctx context.Context, arg IsBlockedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlocked, arg.UserID, arg.OtherID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
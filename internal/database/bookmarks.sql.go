@@ -0,0 +1,129 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: bookmarks.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createBookmark = `-- name: CreateBookmark :one
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING user_id, chirp_id, created_at
+`
+
+type CreateBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) CreateBookmark(ctx context.Context, arg CreateBookmarkParams) (Bookmark, error) {
+	row := q.db.QueryRowContext(ctx, createBookmark, arg.UserID, arg.ChirpID)
+	var i Bookmark
+	err := row.Scan(&i.UserID, &i.ChirpID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteBookmark = `-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2
+`
+
+type DeleteBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBookmark, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const getBookmark = `-- name: GetBookmark :one
+SELECT user_id, chirp_id, created_at FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2
+`
+
+type GetBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) GetBookmark(ctx context.Context, arg GetBookmarkParams) (Bookmark, error) {
+	row := q.db.QueryRowContext(ctx, getBookmark, arg.UserID, arg.ChirpID)
+	var i Bookmark
+	err := row.Scan(&i.UserID, &i.ChirpID, &i.CreatedAt)
+	return i, err
+}
+
+const getBookmarkedChirps = `-- name: GetBookmarkedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.rechirp_of_id, chirps.quoted_chirp_id, chirps.rechirp_count, chirps.quote_count, chirps.deleted_at, chirps.reply_to_id, chirps.hidden_at, chirps.visibility FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+WHERE bookmarks.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+AND (
+  $2::UUID IS NULL
+  OR (bookmarks.created_at, bookmarks.chirp_id) < (
+    SELECT cursor.created_at, cursor.chirp_id FROM bookmarks AS cursor
+    WHERE cursor.user_id = $1
+    AND cursor.chirp_id = $2::UUID
+  )
+)
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT $3
+`
+
+type GetBookmarkedChirpsParams struct {
+	UserID     uuid.UUID
+	Before     uuid.NullUUID
+	MaxResults int32
+}
+
+// Pages are keyed by the chirp ID of the last bookmark on the previous
+// page.
+func (q *Queries) GetBookmarkedChirps(ctx context.Context, arg GetBookmarkedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getBookmarkedChirps, arg.UserID, arg.Before, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
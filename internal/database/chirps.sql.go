@@ -7,29 +7,43 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, reply_to_id, visibility)
 VALUES (
   gen_random_uuid(),
   NOW(),
   NOW(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	QuotedChirpID uuid.NullUUID
+	ReplyToID     uuid.NullUUID
+	Visibility    string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.QuotedChirpID,
+		arg.ReplyToID,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +51,15 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -52,8 +75,9 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
 WHERE id = $1
+AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -65,12 +89,125 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getChirpByIDForUpdate = `-- name: GetChirpByIDForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE id = $1
+AND deleted_at IS NULL
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpByIDForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getChirpByIDWithDeleted = `-- name: GetChirpByIDWithDeleted :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpByIDWithDeleted(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDWithDeleted, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
 	)
 	return i, err
 }
 
+const getChirpReplies = `-- name: GetChirpReplies :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE reply_to_id = $1
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpReplies(ctx context.Context, replyToID uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies, replyToID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE deleted_at IS NULL
+AND hidden_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -89,6 +226,248 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByHashtag = `-- name: GetChirpsByHashtag :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE id IN (
+  SELECT chirp_id FROM chirp_hashtags
+  WHERE tag = $1
+)
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpsByHashtag(ctx context.Context, tag string) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByHashtag, tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE id = ANY($1::UUID[])
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUserID = `-- name: GetChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirps = `-- name: GetDeletedChirps :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) GetDeletedChirps(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirpsByUserID = `-- name: GetDeletedChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE user_id = $1
+AND deleted_at > $2::TIMESTAMP
+ORDER BY deleted_at DESC
+`
+
+type GetDeletedChirpsByUserIDParams struct {
+	UserID       uuid.UUID
+	DeletedAfter time.Time
+}
+
+func (q *Queries) GetDeletedChirpsByUserID(ctx context.Context, arg GetDeletedChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedChirpsByUserID, arg.UserID, arg.DeletedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -102,3 +481,127 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const hideChirp = `-- name: HideChirp :one
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, hideChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at <= $1::TIMESTAMP
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1
+AND user_id = $2
+AND deleted_at > $3::TIMESTAMP
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility
+`
+
+type RestoreChirpParams struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	DeletedAfter time.Time
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.UserID, arg.DeletedAfter)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
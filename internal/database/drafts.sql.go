@@ -0,0 +1,228 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: drafts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const claimDueDraft = `-- name: ClaimDueDraft :one
+SELECT id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, publish_error, visibility FROM chirp_drafts
+WHERE publish_at <= NOW()
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ClaimDueDraft(ctx context.Context) (ChirpDraft, error) {
+	row := q.db.QueryRowContext(ctx, claimDueDraft)
+	var i ChirpDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.QuotedChirpID,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO chirp_drafts (id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, visibility)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, publish_error, visibility
+`
+
+type CreateDraftParams struct {
+	UserID        uuid.UUID
+	Body          string
+	QuotedChirpID uuid.NullUUID
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (ChirpDraft, error) {
+	row := q.db.QueryRowContext(ctx, createDraft,
+		arg.UserID,
+		arg.Body,
+		arg.QuotedChirpID,
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i ChirpDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.QuotedChirpID,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const deleteDraft = `-- name: DeleteDraft :execrows
+DELETE FROM chirp_drafts
+WHERE id = $1
+AND user_id = $2
+`
+
+type DeleteDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteDraft(ctx context.Context, arg DeleteDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDraft, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDraftForUpdate = `-- name: GetDraftForUpdate :one
+SELECT id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, publish_error, visibility FROM chirp_drafts
+WHERE id = $1
+AND user_id = $2
+FOR UPDATE
+`
+
+type GetDraftForUpdateParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraftForUpdate(ctx context.Context, arg GetDraftForUpdateParams) (ChirpDraft, error) {
+	row := q.db.QueryRowContext(ctx, getDraftForUpdate, arg.ID, arg.UserID)
+	var i ChirpDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.QuotedChirpID,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getDraftsByUserID = `-- name: GetDraftsByUserID :many
+SELECT id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, publish_error, visibility FROM chirp_drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC
+`
+
+func (q *Queries) GetDraftsByUserID(ctx context.Context, userID uuid.UUID) ([]ChirpDraft, error) {
+	rows, err := q.db.QueryContext(ctx, getDraftsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpDraft
+	for rows.Next() {
+		var i ChirpDraft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+			&i.QuotedChirpID,
+			&i.PublishAt,
+			&i.PublishError,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setDraftPublishError = `-- name: SetDraftPublishError :exec
+UPDATE chirp_drafts
+SET publish_at = NULL, publish_error = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetDraftPublishErrorParams struct {
+	ID           uuid.UUID
+	PublishError sql.NullString
+}
+
+func (q *Queries) SetDraftPublishError(ctx context.Context, arg SetDraftPublishErrorParams) error {
+	_, err := q.db.ExecContext(ctx, setDraftPublishError, arg.ID, arg.PublishError)
+	return err
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE chirp_drafts
+SET body = $3, quoted_chirp_id = $4, publish_at = $5, visibility = $6, publish_error = NULL, updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, publish_error, visibility
+`
+
+type UpdateDraftParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	QuotedChirpID uuid.NullUUID
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (ChirpDraft, error) {
+	row := q.db.QueryRowContext(ctx, updateDraft,
+		arg.ID,
+		arg.UserID,
+		arg.Body,
+		arg.QuotedChirpID,
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i ChirpDraft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.QuotedChirpID,
+		&i.PublishAt,
+		&i.PublishError,
+		&i.Visibility,
+	)
+	return i, err
+}
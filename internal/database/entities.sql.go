@@ -0,0 +1,113 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: entities.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createChirpHashtag = `-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, start_index, end_index)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateChirpHashtagParams struct {
+	ChirpID    uuid.UUID
+	Tag        string
+	StartIndex int32
+	EndIndex   int32
+}
+
+func (q *Queries) CreateChirpHashtag(ctx context.Context, arg CreateChirpHashtagParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpHashtag,
+		arg.ChirpID,
+		arg.Tag,
+		arg.StartIndex,
+		arg.EndIndex,
+	)
+	return err
+}
+
+const createChirpMention = `-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, username, user_id, start_index, end_index)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateChirpMentionParams struct {
+	ChirpID    uuid.UUID
+	Username   string
+	UserID     uuid.NullUUID
+	StartIndex int32
+	EndIndex   int32
+}
+
+func (q *Queries) CreateChirpMention(ctx context.Context, arg CreateChirpMentionParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpMention,
+		arg.ChirpID,
+		arg.Username,
+		arg.UserID,
+		arg.StartIndex,
+		arg.EndIndex,
+	)
+	return err
+}
+
+const deleteChirpHashtags = `-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpHashtags(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpHashtags, chirpID)
+	return err
+}
+
+const deleteChirpMentions = `-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpMentions(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpMentions, chirpID)
+	return err
+}
+
+const getChirpMentionsByChirpIDs = `-- name: GetChirpMentionsByChirpIDs :many
+SELECT chirp_id, username, user_id, start_index, end_index FROM chirp_mentions
+WHERE chirp_id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetChirpMentionsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMentionsByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpMention
+	for rows.Next() {
+		var i ChirpMention
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Username,
+			&i.UserID,
+			&i.StartIndex,
+			&i.EndIndex,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
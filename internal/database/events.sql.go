@@ -0,0 +1,44 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: events.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const notifyChirpEvent = `-- name: NotifyChirpEvent :exec
+SELECT pg_notify(
+  'chirp_events',
+  json_build_object(
+    'id', nextval('chirp_event_seq'),
+    'type', $1::TEXT,
+    'chirp_id', $2::UUID,
+    'user_id', $3::UUID
+  )::TEXT
+)
+`
+
+type NotifyChirpEventParams struct {
+	Type    string
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) NotifyChirpEvent(ctx context.Context, arg NotifyChirpEventParams) error {
+	_, err := q.db.ExecContext(ctx, notifyChirpEvent, arg.Type, arg.ChirpID, arg.UserID)
+	return err
+}
+
+const notifyUser = `-- name: NotifyUser :exec
+SELECT pg_notify('user_notifications', $1::TEXT)
+`
+
+func (q *Queries) NotifyUser(ctx context.Context, payload string) error {
+	_, err := q.db.ExecContext(ctx, notifyUser, payload)
+	return err
+}
@@ -0,0 +1,275 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: follows.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const approveAllFollowRequests = `-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+  DELETE FROM follow_requests
+  WHERE target_id = $1
+  RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW() FROM approved
+ON CONFLICT DO NOTHING
+RETURNING follower_id, followee_id, created_at
+`
+
+func (q *Queries) ApproveAllFollowRequests(ctx context.Context, targetID uuid.UUID) ([]Follow, error) {
+	rows, err := q.db.QueryContext(ctx, approveAllFollowRequests, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Follow
+	for rows.Next() {
+		var i Follow
+		if err := rows.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createFollow = `-- name: CreateFollow :one
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING follower_id, followee_id, created_at
+`
+
+type CreateFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) CreateFollow(ctx context.Context, arg CreateFollowParams) (Follow, error) {
+	row := q.db.QueryRowContext(ctx, createFollow, arg.FollowerID, arg.FolloweeID)
+	var i Follow
+	err := row.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt)
+	return i, err
+}
+
+const createFollowRequest = `-- name: CreateFollowRequest :one
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING requester_id, target_id, created_at
+`
+
+type CreateFollowRequestParams struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (q *Queries) CreateFollowRequest(ctx context.Context, arg CreateFollowRequestParams) (FollowRequest, error) {
+	row := q.db.QueryRowContext(ctx, createFollowRequest, arg.RequesterID, arg.TargetID)
+	var i FollowRequest
+	err := row.Scan(&i.RequesterID, &i.TargetID, &i.CreatedAt)
+	return i, err
+}
+
+const deleteFollow = `-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2
+`
+
+type DeleteFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollow, arg.FollowerID, arg.FolloweeID)
+	return err
+}
+
+const deleteFollowRequest = `-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1
+AND target_id = $2
+`
+
+type DeleteFollowRequestParams struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (q *Queries) DeleteFollowRequest(ctx context.Context, arg DeleteFollowRequestParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFollowRequest, arg.RequesterID, arg.TargetID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFollow = `-- name: GetFollow :one
+SELECT follower_id, followee_id, created_at FROM follows
+WHERE follower_id = $1
+AND followee_id = $2
+`
+
+type GetFollowParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) GetFollow(ctx context.Context, arg GetFollowParams) (Follow, error) {
+	row := q.db.QueryRowContext(ctx, getFollow, arg.FollowerID, arg.FolloweeID)
+	var i Follow
+	err := row.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt)
+	return i, err
+}
+
+const getFollowRequest = `-- name: GetFollowRequest :one
+SELECT requester_id, target_id, created_at FROM follow_requests
+WHERE requester_id = $1
+AND target_id = $2
+`
+
+type GetFollowRequestParams struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (q *Queries) GetFollowRequest(ctx context.Context, arg GetFollowRequestParams) (FollowRequest, error) {
+	row := q.db.QueryRowContext(ctx, getFollowRequest, arg.RequesterID, arg.TargetID)
+	var i FollowRequest
+	err := row.Scan(&i.RequesterID, &i.TargetID, &i.CreatedAt)
+	return i, err
+}
+
+const getFollowRequests = `-- name: GetFollowRequests :many
+SELECT follow_requests.requester_id AS user_id, users.username, follow_requests.created_at
+FROM follow_requests
+JOIN users ON users.id = follow_requests.requester_id
+WHERE follow_requests.target_id = $1
+ORDER BY follow_requests.created_at ASC
+`
+
+type GetFollowRequestsRow struct {
+	UserID    uuid.UUID
+	Username  sql.NullString
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetFollowRequests(ctx context.Context, targetID uuid.UUID) ([]GetFollowRequestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowRequests, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowRequestsRow
+	for rows.Next() {
+		var i GetFollowRequestsRow
+		if err := rows.Scan(&i.UserID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFolloweeIDs = `-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1
+`
+
+func (q *Queries) GetFolloweeIDs(ctx context.Context, followerID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getFolloweeIDs, followerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var followee_id uuid.UUID
+		if err := rows.Scan(&followee_id); err != nil {
+			return nil, err
+		}
+		items = append(items, followee_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimelineChirps = `-- name: GetTimelineChirps :many
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE deleted_at IS NULL
+AND hidden_at IS NULL
+AND (
+  user_id = $1
+  OR user_id IN (
+    SELECT followee_id FROM follows
+    WHERE follower_id = $1
+  )
+)
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetTimelineChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimelineChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
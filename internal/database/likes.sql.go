@@ -0,0 +1,195 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: likes.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getChirpLikes = `-- name: GetChirpLikes :many
+SELECT chirp_likes.user_id, chirp_likes.created_at, users.username
+FROM chirp_likes
+JOIN users ON users.id = chirp_likes.user_id
+WHERE chirp_likes.chirp_id = $1
+ORDER BY chirp_likes.created_at DESC
+`
+
+type GetChirpLikesRow struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Username  sql.NullString
+}
+
+func (q *Queries) GetChirpLikes(ctx context.Context, chirpID uuid.UUID) ([]GetChirpLikesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpLikes, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpLikesRow
+	for rows.Next() {
+		var i GetChirpLikesRow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsLikedByUser = `-- name: GetChirpsLikedByUser :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.rechirp_of_id, chirps.quoted_chirp_id, chirps.rechirp_count, chirps.quote_count, chirps.deleted_at, chirps.reply_to_id, chirps.hidden_at, chirps.visibility FROM chirps
+JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_likes.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY chirp_likes.created_at DESC
+`
+
+func (q *Queries) GetChirpsLikedByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsLikedByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLikedChirpIDs = `-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = $1
+AND chirp_id = ANY($2::UUID[])
+`
+
+type GetLikedChirpIDsParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetLikedChirpIDs(ctx context.Context, arg GetLikedChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getLikedChirpIDs, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var chirp_id uuid.UUID
+		if err := rows.Scan(&chirp_id); err != nil {
+			return nil, err
+		}
+		items = append(items, chirp_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const likeChirp = `-- name: LikeChirp :exec
+WITH inserted AS (
+  INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+  VALUES ($1, $2, NOW())
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count + 1
+WHERE id IN (SELECT chirp_id FROM inserted)
+`
+
+type LikeChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) LikeChirp(ctx context.Context, arg LikeChirpParams) error {
+	_, err := q.db.ExecContext(ctx, likeChirp, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const removeLikesOfUsersPastGracePeriod = `-- name: RemoveLikesOfUsersPastGracePeriod :exec
+UPDATE chirps
+SET like_count = chirps.like_count - purged.likes
+FROM (
+  SELECT chirp_likes.chirp_id, COUNT(*) AS likes
+  FROM chirp_likes
+  JOIN users ON users.id = chirp_likes.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  GROUP BY chirp_likes.chirp_id
+) AS purged
+WHERE chirps.id = purged.chirp_id
+`
+
+func (q *Queries) RemoveLikesOfUsersPastGracePeriod(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, removeLikesOfUsersPastGracePeriod)
+	return err
+}
+
+const unlikeChirp = `-- name: UnlikeChirp :exec
+WITH deleted AS (
+  DELETE FROM chirp_likes
+  WHERE chirp_likes.user_id = $1 AND chirp_likes.chirp_id = $2
+  RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted)
+`
+
+type UnlikeChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) UnlikeChirp(ctx context.Context, arg UnlikeChirpParams) error {
+	_, err := q.db.ExecContext(ctx, unlikeChirp, arg.UserID, arg.ChirpID)
+	return err
+}
@@ -0,0 +1,324 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: lists.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addListMember = `-- name: AddListMember :one
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING list_id, user_id, created_at
+`
+
+type AddListMemberParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) AddListMember(ctx context.Context, arg AddListMemberParams) (ListMember, error) {
+	row := q.db.QueryRowContext(ctx, addListMember, arg.ListID, arg.UserID)
+	var i ListMember
+	err := row.Scan(&i.ListID, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const countListMembers = `-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1
+`
+
+func (q *Queries) CountListMembers(ctx context.Context, listID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countListMembers, listID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countListsByOwner = `-- name: CountListsByOwner :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1
+`
+
+func (q *Queries) CountListsByOwner(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countListsByOwner, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createList = `-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, private)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING id, created_at, updated_at, owner_id, name, description, private
+`
+
+type CreateListParams struct {
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Private     bool
+}
+
+func (q *Queries) CreateList(ctx context.Context, arg CreateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, createList,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.Private,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Private,
+	)
+	return i, err
+}
+
+const deleteList = `-- name: DeleteList :exec
+DELETE FROM lists
+WHERE id = $1
+AND owner_id = $2
+`
+
+type DeleteListParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.UUID
+}
+
+func (q *Queries) DeleteList(ctx context.Context, arg DeleteListParams) error {
+	_, err := q.db.ExecContext(ctx, deleteList, arg.ID, arg.OwnerID)
+	return err
+}
+
+const deleteListMember = `-- name: DeleteListMember :exec
+DELETE FROM list_members
+WHERE list_id = $1
+AND user_id = $2
+`
+
+type DeleteListMemberParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteListMember(ctx context.Context, arg DeleteListMemberParams) error {
+	_, err := q.db.ExecContext(ctx, deleteListMember, arg.ListID, arg.UserID)
+	return err
+}
+
+const getList = `-- name: GetList :one
+SELECT id, created_at, updated_at, owner_id, name, description, private FROM lists
+WHERE id = $1
+`
+
+func (q *Queries) GetList(ctx context.Context, id uuid.UUID) (List, error) {
+	row := q.db.QueryRowContext(ctx, getList, id)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Private,
+	)
+	return i, err
+}
+
+const getListChirps = `-- name: GetListChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.rechirp_of_id, chirps.quoted_chirp_id, chirps.rechirp_count, chirps.quote_count, chirps.deleted_at, chirps.reply_to_id, chirps.hidden_at, chirps.visibility FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+WHERE list_members.list_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY chirps.created_at DESC
+`
+
+func (q *Queries) GetListChirps(ctx context.Context, listID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getListChirps, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListMember = `-- name: GetListMember :one
+SELECT list_id, user_id, created_at FROM list_members
+WHERE list_id = $1
+AND user_id = $2
+`
+
+type GetListMemberParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetListMember(ctx context.Context, arg GetListMemberParams) (ListMember, error) {
+	row := q.db.QueryRowContext(ctx, getListMember, arg.ListID, arg.UserID)
+	var i ListMember
+	err := row.Scan(&i.ListID, &i.UserID, &i.CreatedAt)
+	return i, err
+}
+
+const getListMembers = `-- name: GetListMembers :many
+SELECT list_members.user_id, users.username, list_members.created_at
+FROM list_members
+JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1
+ORDER BY list_members.created_at ASC
+`
+
+type GetListMembersRow struct {
+	UserID    uuid.UUID
+	Username  sql.NullString
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetListMembers(ctx context.Context, listID uuid.UUID) ([]GetListMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getListMembers, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetListMembersRow
+	for rows.Next() {
+		var i GetListMembersRow
+		if err := rows.Scan(&i.UserID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListsByOwner = `-- name: GetListsByOwner :many
+SELECT id, created_at, updated_at, owner_id, name, description, private FROM lists
+WHERE owner_id = $1
+AND ($2::BOOLEAN OR NOT private)
+ORDER BY created_at ASC
+`
+
+type GetListsByOwnerParams struct {
+	OwnerID        uuid.UUID
+	IncludePrivate bool
+}
+
+func (q *Queries) GetListsByOwner(ctx context.Context, arg GetListsByOwnerParams) ([]List, error) {
+	rows, err := q.db.QueryContext(ctx, getListsByOwner, arg.OwnerID, arg.IncludePrivate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []List
+	for rows.Next() {
+		var i List
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.Private,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateList = `-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, private = $5, updated_at = NOW()
+WHERE id = $1
+AND owner_id = $2
+RETURNING id, created_at, updated_at, owner_id, name, description, private
+`
+
+type UpdateListParams struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Private     bool
+}
+
+func (q *Queries) UpdateList(ctx context.Context, arg UpdateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, updateList,
+		arg.ID,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.Private,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Private,
+	)
+	return i, err
+}
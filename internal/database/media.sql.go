@@ -0,0 +1,216 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attachMedia = `-- name: AttachMedia :one
+UPDATE media
+SET chirp_id = $1::UUID, position = $2::INTEGER, alt_text = $3
+WHERE id = $4
+AND user_id = $5::UUID
+AND chirp_id IS NULL
+RETURNING id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key, chirp_id, position, alt_text
+`
+
+type AttachMediaParams struct {
+	ChirpID  uuid.UUID
+	Position int32
+	AltText  string
+	ID       uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) AttachMedia(ctx context.Context, arg AttachMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, attachMedia,
+		arg.ChirpID,
+		arg.Position,
+		arg.AltText,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ContentType,
+		&i.ByteSize,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ChirpID,
+		&i.Position,
+		&i.AltText,
+	)
+	return i, err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key, chirp_id, position, alt_text
+`
+
+type CreateMediaParams struct {
+	ID           uuid.UUID
+	UserID       uuid.NullUUID
+	ContentType  string
+	ByteSize     int32
+	Width        int32
+	Height       int32
+	StorageKey   string
+	ThumbnailKey string
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.ID,
+		arg.UserID,
+		arg.ContentType,
+		arg.ByteSize,
+		arg.Width,
+		arg.Height,
+		arg.StorageKey,
+		arg.ThumbnailKey,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ContentType,
+		&i.ByteSize,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ChirpID,
+		&i.Position,
+		&i.AltText,
+	)
+	return i, err
+}
+
+const getMediaByChirpIDs = `-- name: GetMediaByChirpIDs :many
+SELECT id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key, chirp_id, position, alt_text FROM media
+WHERE chirp_id = ANY($1::UUID[])
+ORDER BY chirp_id, position
+`
+
+func (q *Queries) GetMediaByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]Media, error) {
+	rows, err := q.db.QueryContext(ctx, getMediaByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Media
+	for rows.Next() {
+		var i Media
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ContentType,
+			&i.ByteSize,
+			&i.Width,
+			&i.Height,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ChirpID,
+			&i.Position,
+			&i.AltText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaByKey = `-- name: GetMediaByKey :one
+SELECT id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key, chirp_id, position, alt_text FROM media
+WHERE storage_key = $1
+OR thumbnail_key = $1
+`
+
+func (q *Queries) GetMediaByKey(ctx context.Context, key string) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByKey, key)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ContentType,
+		&i.ByteSize,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ChirpID,
+		&i.Position,
+		&i.AltText,
+	)
+	return i, err
+}
+
+const purgeUnattachedMedia = `-- name: PurgeUnattachedMedia :many
+DELETE FROM media
+WHERE chirp_id IS NULL
+AND created_at < $1
+RETURNING storage_key, thumbnail_key
+`
+
+type PurgeUnattachedMediaRow struct {
+	StorageKey   string
+	ThumbnailKey string
+}
+
+func (q *Queries) PurgeUnattachedMedia(ctx context.Context, createdAt time.Time) ([]PurgeUnattachedMediaRow, error) {
+	rows, err := q.db.QueryContext(ctx, purgeUnattachedMedia, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PurgeUnattachedMediaRow
+	for rows.Next() {
+		var i PurgeUnattachedMediaRow
+		if err := rows.Scan(&i.StorageKey, &i.ThumbnailKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,406 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: messages.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addConversationMember = `-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at, last_read_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT DO NOTHING
+`
+
+type AddConversationMemberParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) AddConversationMember(ctx context.Context, arg AddConversationMemberParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationMember, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const countUnreadMessages = `-- name: CountUnreadMessages :one
+SELECT COUNT(*) FROM messages
+JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+WHERE conversation_members.user_id = $1
+AND ($2::UUID IS NULL OR messages.conversation_id = $2::UUID)
+AND messages.sender_id <> $1
+AND messages.created_at > conversation_members.last_read_at
+AND NOT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocks.blocker_id = $1 AND blocks.blocked_id = messages.sender_id)
+  OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = $1)
+)
+`
+
+type CountUnreadMessagesParams struct {
+	UserID         uuid.UUID
+	ConversationID uuid.NullUUID
+}
+
+// Counts across all of the user's conversations unless one is given.
+// Messages from users blocked either way aren't counted, as in
+// GetConversations.
+func (q *Queries) CountUnreadMessages(ctx context.Context, arg CountUnreadMessagesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadMessages, arg.UserID, arg.ConversationID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createConversation = `-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING id, created_at, updated_at, created_by, direct_key
+`
+
+type CreateConversationParams struct {
+	CreatedBy uuid.NullUUID
+	DirectKey sql.NullString
+}
+
+// Returns no rows if a one-to-one conversation with the key already
+// exists.
+func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createConversation, arg.CreatedBy, arg.DirectKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING id, created_at, conversation_id, sender_id, body
+`
+
+type CreateMessageParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createMessage, arg.ConversationID, arg.SenderID, arg.Body)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const getConversationByDirectKey = `-- name: GetConversationByDirectKey :one
+SELECT id, created_at, updated_at, created_by, direct_key FROM conversations
+WHERE direct_key = $1
+`
+
+func (q *Queries) GetConversationByDirectKey(ctx context.Context, directKey sql.NullString) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationByDirectKey, directKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const getConversationForMember = `-- name: GetConversationForMember :one
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.direct_key FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = $1
+AND conversation_members.user_id = $2
+`
+
+type GetConversationForMemberParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetConversationForMember(ctx context.Context, arg GetConversationForMemberParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationForMember, arg.ID, arg.UserID)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.DirectKey,
+	)
+	return i, err
+}
+
+const getConversationMembers = `-- name: GetConversationMembers :many
+SELECT conversation_members.conversation_id, conversation_members.user_id, users.username,
+  conversation_members.joined_at, conversation_members.last_read_at
+FROM conversation_members
+JOIN users ON users.id = conversation_members.user_id
+WHERE conversation_members.conversation_id = ANY($1::UUID[])
+ORDER BY conversation_members.joined_at ASC, conversation_members.user_id ASC
+`
+
+type GetConversationMembersRow struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	Username       sql.NullString
+	JoinedAt       time.Time
+	LastReadAt     time.Time
+}
+
+func (q *Queries) GetConversationMembers(ctx context.Context, conversationIds []uuid.UUID) ([]GetConversationMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationMembers, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetConversationMembersRow
+	for rows.Next() {
+		var i GetConversationMembersRow
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.UserID,
+			&i.Username,
+			&i.JoinedAt,
+			&i.LastReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversations = `-- name: GetConversations :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.created_by, conversations.direct_key, (
+  SELECT COUNT(*) FROM messages
+  WHERE messages.conversation_id = conversations.id
+  AND messages.sender_id <> $1
+  AND messages.created_at > conversation_members.last_read_at
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = $1 AND blocks.blocked_id = messages.sender_id)
+    OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = $1)
+  )
+) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = $1
+ORDER BY conversations.updated_at DESC
+`
+
+type GetConversationsRow struct {
+	Conversation Conversation
+	UnreadCount  int64
+}
+
+// Messages from users blocked either way aren't counted as unread, since
+// they're left out of the conversation's messages.
+func (q *Queries) GetConversations(ctx context.Context, userID uuid.UUID) ([]GetConversationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getConversations, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetConversationsRow
+	for rows.Next() {
+		var i GetConversationsRow
+		if err := rows.Scan(
+			&i.Conversation.ID,
+			&i.Conversation.CreatedAt,
+			&i.Conversation.UpdatedAt,
+			&i.Conversation.CreatedBy,
+			&i.Conversation.DirectKey,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestMessages = `-- name: GetLatestMessages :many
+SELECT DISTINCT ON (conversation_id) id, created_at, conversation_id, sender_id, body FROM messages
+WHERE conversation_id = ANY($1::UUID[])
+AND NOT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocks.blocker_id = $2 AND blocks.blocked_id = messages.sender_id)
+  OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = $2)
+)
+ORDER BY conversation_id, created_at DESC, id DESC
+`
+
+type GetLatestMessagesParams struct {
+	ConversationIds []uuid.UUID
+	UserID          uuid.UUID
+}
+
+// The newest message in each conversation that user_id can see, leaving
+// out senders blocked either way.
+func (q *Queries) GetLatestMessages(ctx context.Context, arg GetLatestMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getLatestMessages, pq.Array(arg.ConversationIds), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, created_at, conversation_id, sender_id, body FROM messages
+WHERE id = $1
+AND conversation_id = $2
+`
+
+type GetMessageParams struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, getMessage, arg.ID, arg.ConversationID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+	)
+	return i, err
+}
+
+const getMessages = `-- name: GetMessages :many
+SELECT id, created_at, conversation_id, sender_id, body FROM messages
+WHERE messages.conversation_id = $1
+AND (
+  $2::UUID IS NULL
+  OR (messages.created_at, messages.id) < (
+    SELECT cursor.created_at, cursor.id FROM messages AS cursor
+    WHERE cursor.id = $2::UUID
+  )
+)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT $3
+`
+
+type GetMessagesParams struct {
+	ConversationID uuid.UUID
+	Before         uuid.NullUUID
+	MaxResults     int32
+}
+
+func (q *Queries) GetMessages(ctx context.Context, arg GetMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getMessages, arg.ConversationID, arg.Before, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationRead = `-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = GREATEST(last_read_at, $1::TIMESTAMP)
+WHERE conversation_id = $2
+AND user_id = $3
+`
+
+type MarkConversationReadParams struct {
+	ReadAt         time.Time
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+// Read receipts only move forward.
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
+	_, err := q.db.ExecContext(ctx, markConversationRead, arg.ReadAt, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const touchConversation = `-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TouchConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchConversation, id)
+	return err
+}
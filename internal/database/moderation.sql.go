@@ -0,0 +1,605 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: moderation.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimModerationCase = `-- name: ClaimModerationCase :one
+UPDATE moderation_cases
+SET status = 'claimed', claimed_by = $1, claimed_at = NOW(), updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at
+`
+
+type ClaimModerationCaseParams struct {
+	ClaimedBy uuid.NullUUID
+	ID        uuid.UUID
+}
+
+func (q *Queries) ClaimModerationCase(ctx context.Context, arg ClaimModerationCaseParams) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, claimModerationCase, arg.ClaimedBy, arg.ID)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const createChirpFlag = `-- name: CreateChirpFlag :one
+INSERT INTO chirp_flags (id, created_at, chirp_id, reasons, case_id)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3
+)
+RETURNING id, created_at, chirp_id, reasons, case_id
+`
+
+type CreateChirpFlagParams struct {
+	ChirpID uuid.UUID
+	Reasons []string
+	CaseID  uuid.NullUUID
+}
+
+func (q *Queries) CreateChirpFlag(ctx context.Context, arg CreateChirpFlagParams) (ChirpFlag, error) {
+	row := q.db.QueryRowContext(ctx, createChirpFlag, arg.ChirpID, pq.Array(arg.Reasons), arg.CaseID)
+	var i ChirpFlag
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ChirpID,
+		pq.Array(&i.Reasons),
+		&i.CaseID,
+	)
+	return i, err
+}
+
+const createModerationDecision = `-- name: CreateModerationDecision :one
+INSERT INTO moderation_decisions (id, created_at, case_id, moderator_id, action, note)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING id, created_at, case_id, moderator_id, action, note
+`
+
+type CreateModerationDecisionParams struct {
+	CaseID      uuid.UUID
+	ModeratorID uuid.NullUUID
+	Action      string
+	Note        string
+}
+
+func (q *Queries) CreateModerationDecision(ctx context.Context, arg CreateModerationDecisionParams) (ModerationDecision, error) {
+	row := q.db.QueryRowContext(ctx, createModerationDecision,
+		arg.CaseID,
+		arg.ModeratorID,
+		arg.Action,
+		arg.Note,
+	)
+	var i ModerationDecision
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.CaseID,
+		&i.ModeratorID,
+		&i.Action,
+		&i.Note,
+	)
+	return i, err
+}
+
+const createModerationTerm = `-- name: CreateModerationTerm :one
+INSERT INTO moderation_terms (id, created_at, updated_at, kind, pattern, action)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3
+)
+RETURNING id, created_at, updated_at, kind, pattern, action
+`
+
+type CreateModerationTermParams struct {
+	Kind    string
+	Pattern string
+	Action  string
+}
+
+func (q *Queries) CreateModerationTerm(ctx context.Context, arg CreateModerationTermParams) (ModerationTerm, error) {
+	row := q.db.QueryRowContext(ctx, createModerationTerm, arg.Kind, arg.Pattern, arg.Action)
+	var i ModerationTerm
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		&i.Pattern,
+		&i.Action,
+	)
+	return i, err
+}
+
+const createReport = `-- name: CreateReport :one
+INSERT INTO reports (id, created_at, case_id, chirp_id, reporter_id, reason, details)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING
+RETURNING id, created_at, case_id, chirp_id, reporter_id, reason, details
+`
+
+type CreateReportParams struct {
+	CaseID     uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+}
+
+func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, createReport,
+		arg.CaseID,
+		arg.ChirpID,
+		arg.ReporterID,
+		arg.Reason,
+		arg.Details,
+	)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.CaseID,
+		&i.ChirpID,
+		&i.ReporterID,
+		&i.Reason,
+		&i.Details,
+	)
+	return i, err
+}
+
+const deleteModerationTerm = `-- name: DeleteModerationTerm :execrows
+DELETE FROM moderation_terms
+WHERE id = $1
+`
+
+func (q *Queries) DeleteModerationTerm(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteModerationTerm, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getCaseChirpFlags = `-- name: GetCaseChirpFlags :many
+SELECT id, created_at, chirp_id, reasons, case_id FROM chirp_flags
+WHERE case_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetCaseChirpFlags(ctx context.Context, caseID uuid.NullUUID) ([]ChirpFlag, error) {
+	rows, err := q.db.QueryContext(ctx, getCaseChirpFlags, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpFlag
+	for rows.Next() {
+		var i ChirpFlag
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			pq.Array(&i.Reasons),
+			&i.CaseID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCaseReports = `-- name: GetCaseReports :many
+SELECT id, created_at, case_id, chirp_id, reporter_id, reason, details FROM reports
+WHERE case_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetCaseReports(ctx context.Context, caseID uuid.UUID) ([]Report, error) {
+	rows, err := q.db.QueryContext(ctx, getCaseReports, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Report
+	for rows.Next() {
+		var i Report
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.CaseID,
+			&i.ChirpID,
+			&i.ReporterID,
+			&i.Reason,
+			&i.Details,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpFlags = `-- name: GetChirpFlags :many
+SELECT id, created_at, chirp_id, reasons, case_id FROM chirp_flags
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpFlags(ctx context.Context) ([]ChirpFlag, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpFlags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpFlag
+	for rows.Next() {
+		var i ChirpFlag
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			pq.Array(&i.Reasons),
+			&i.CaseID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModerationCase = `-- name: GetModerationCase :one
+SELECT id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at FROM moderation_cases
+WHERE id = $1
+`
+
+func (q *Queries) GetModerationCase(ctx context.Context, id uuid.UUID) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, getModerationCase, id)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getModerationCaseForUpdate = `-- name: GetModerationCaseForUpdate :one
+SELECT id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at FROM moderation_cases
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetModerationCaseForUpdate(ctx context.Context, id uuid.UUID) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, getModerationCaseForUpdate, id)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const getModerationCases = `-- name: GetModerationCases :many
+SELECT moderation_cases.id, moderation_cases.created_at, moderation_cases.updated_at, moderation_cases.chirp_id, moderation_cases.status, moderation_cases.claimed_by, moderation_cases.claimed_at, moderation_cases.resolution, moderation_cases.resolved_by, moderation_cases.resolved_at,
+  (SELECT COUNT(*) FROM reports WHERE reports.case_id = moderation_cases.id) AS report_count,
+  EXISTS (SELECT 1 FROM chirp_flags WHERE chirp_flags.case_id = moderation_cases.id) AS flagged
+FROM moderation_cases
+WHERE (
+  $1::TEXT IS NULL AND status <> 'resolved'
+  OR status = $1::TEXT
+)
+AND ($2::UUID IS NULL OR claimed_by = $2::UUID)
+ORDER BY created_at ASC
+LIMIT $3
+`
+
+type GetModerationCasesParams struct {
+	Status     sql.NullString
+	ClaimedBy  uuid.NullUUID
+	MaxResults int32
+}
+
+type GetModerationCasesRow struct {
+	ModerationCase ModerationCase
+	ReportCount    int64
+	Flagged        bool
+}
+
+func (q *Queries) GetModerationCases(ctx context.Context, arg GetModerationCasesParams) ([]GetModerationCasesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationCases, arg.Status, arg.ClaimedBy, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetModerationCasesRow
+	for rows.Next() {
+		var i GetModerationCasesRow
+		if err := rows.Scan(
+			&i.ModerationCase.ID,
+			&i.ModerationCase.CreatedAt,
+			&i.ModerationCase.UpdatedAt,
+			&i.ModerationCase.ChirpID,
+			&i.ModerationCase.Status,
+			&i.ModerationCase.ClaimedBy,
+			&i.ModerationCase.ClaimedAt,
+			&i.ModerationCase.Resolution,
+			&i.ModerationCase.ResolvedBy,
+			&i.ModerationCase.ResolvedAt,
+			&i.ReportCount,
+			&i.Flagged,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModerationDecisions = `-- name: GetModerationDecisions :many
+SELECT id, created_at, case_id, moderator_id, action, note FROM moderation_decisions
+WHERE case_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetModerationDecisions(ctx context.Context, caseID uuid.UUID) ([]ModerationDecision, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationDecisions, caseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationDecision
+	for rows.Next() {
+		var i ModerationDecision
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.CaseID,
+			&i.ModeratorID,
+			&i.Action,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getModerationTerms = `-- name: GetModerationTerms :many
+SELECT id, created_at, updated_at, kind, pattern, action FROM moderation_terms
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetModerationTerms(ctx context.Context) ([]ModerationTerm, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationTerms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationTerm
+	for rows.Next() {
+		var i ModerationTerm
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Kind,
+			&i.Pattern,
+			&i.Action,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReportByReporter = `-- name: GetReportByReporter :one
+SELECT id, created_at, case_id, chirp_id, reporter_id, reason, details FROM reports
+WHERE chirp_id = $1
+AND reporter_id = $2
+`
+
+type GetReportByReporterParams struct {
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+}
+
+func (q *Queries) GetReportByReporter(ctx context.Context, arg GetReportByReporterParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, getReportByReporter, arg.ChirpID, arg.ReporterID)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.CaseID,
+		&i.ChirpID,
+		&i.ReporterID,
+		&i.Reason,
+		&i.Details,
+	)
+	return i, err
+}
+
+const openModerationCase = `-- name: OpenModerationCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, status)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  'open'
+)
+ON CONFLICT (chirp_id) WHERE status <> 'resolved'
+DO UPDATE SET updated_at = NOW()
+RETURNING id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at
+`
+
+func (q *Queries) OpenModerationCase(ctx context.Context, chirpID uuid.UUID) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, openModerationCase, chirpID)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const releaseModerationCase = `-- name: ReleaseModerationCase :one
+UPDATE moderation_cases
+SET status = 'open', claimed_by = NULL, claimed_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at
+`
+
+func (q *Queries) ReleaseModerationCase(ctx context.Context, id uuid.UUID) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, releaseModerationCase, id)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
+
+const resolveModerationCase = `-- name: ResolveModerationCase :one
+UPDATE moderation_cases
+SET status = 'resolved', resolution = $1, resolved_by = $2,
+  resolved_at = NOW(), updated_at = NOW()
+WHERE id = $3
+RETURNING id, created_at, updated_at, chirp_id, status, claimed_by, claimed_at, resolution, resolved_by, resolved_at
+`
+
+type ResolveModerationCaseParams struct {
+	Resolution sql.NullString
+	ResolvedBy uuid.NullUUID
+	ID         uuid.UUID
+}
+
+func (q *Queries) ResolveModerationCase(ctx context.Context, arg ResolveModerationCaseParams) (ModerationCase, error) {
+	row := q.db.QueryRowContext(ctx, resolveModerationCase, arg.Resolution, arg.ResolvedBy, arg.ID)
+	var i ModerationCase
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChirpID,
+		&i.Status,
+		&i.ClaimedBy,
+		&i.ClaimedAt,
+		&i.Resolution,
+		&i.ResolvedBy,
+		&i.ResolvedAt,
+	)
+	return i, err
+}
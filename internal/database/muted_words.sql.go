@@ -0,0 +1,180 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: muted_words.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const countActiveMutedWords = `-- name: CountActiveMutedWords :one
+SELECT COUNT(*) FROM muted_words
+WHERE user_id = $1
+AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+func (q *Queries) CountActiveMutedWords(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveMutedWords, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteExpiredMutedWords = `-- name: DeleteExpiredMutedWords :execrows
+DELETE FROM muted_words
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredMutedWords(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredMutedWords)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteMutedWord = `-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1
+AND user_id = $2
+`
+
+type DeleteMutedWordParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteMutedWord(ctx context.Context, arg DeleteMutedWordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMutedWord, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getActiveMutedWords = `-- name: GetActiveMutedWords :many
+SELECT id, created_at, updated_at, user_id, kind, pattern, action, expires_at FROM muted_words
+WHERE user_id = $1
+AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetActiveMutedWords(ctx context.Context, userID uuid.UUID) ([]MutedWord, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveMutedWords, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MutedWord
+	for rows.Next() {
+		var i MutedWord
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Kind,
+			&i.Pattern,
+			&i.Action,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateMutedWord = `-- name: UpdateMutedWord :one
+UPDATE muted_words
+SET action = $1, expires_at = $2, updated_at = NOW()
+WHERE id = $3
+AND user_id = $4
+RETURNING id, created_at, updated_at, user_id, kind, pattern, action, expires_at
+`
+
+type UpdateMutedWordParams struct {
+	Action    string
+	ExpiresAt sql.NullTime
+	ID        uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) UpdateMutedWord(ctx context.Context, arg UpdateMutedWordParams) (MutedWord, error) {
+	row := q.db.QueryRowContext(ctx, updateMutedWord,
+		arg.Action,
+		arg.ExpiresAt,
+		arg.ID,
+		arg.UserID,
+	)
+	var i MutedWord
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Kind,
+		&i.Pattern,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const upsertMutedWord = `-- name: UpsertMutedWord :one
+INSERT INTO muted_words (id, created_at, updated_at, user_id, kind, pattern, action, expires_at)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (user_id, kind, pattern) DO UPDATE
+SET action = EXCLUDED.action, expires_at = EXCLUDED.expires_at, updated_at = NOW()
+RETURNING id, created_at, updated_at, user_id, kind, pattern, action, expires_at
+`
+
+type UpsertMutedWordParams struct {
+	UserID    uuid.UUID
+	Kind      string
+	Pattern   string
+	Action    string
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) UpsertMutedWord(ctx context.Context, arg UpsertMutedWordParams) (MutedWord, error) {
+	row := q.db.QueryRowContext(ctx, upsertMutedWord,
+		arg.UserID,
+		arg.Kind,
+		arg.Pattern,
+		arg.Action,
+		arg.ExpiresAt,
+	)
+	var i MutedWord
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Kind,
+		&i.Pattern,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: notifications.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL
+`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createNotification = `-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id, event_key)
+SELECT gen_random_uuid(), NOW(), $1::UUID, $2::TEXT, $3::UUID, $4::UUID, $5::TEXT
+WHERE NOT EXISTS (
+  SELECT 1 FROM notification_preferences
+  WHERE notification_preferences.user_id = $1::UUID
+  AND notification_preferences.type = $2::TEXT
+  AND NOT notification_preferences.enabled
+)
+ON CONFLICT (user_id, event_key) DO NOTHING
+RETURNING id, created_at, user_id, type, actor_id, chirp_id, read_at, event_key
+`
+
+type CreateNotificationParams struct {
+	UserID   uuid.UUID
+	Type     string
+	ActorID  uuid.NullUUID
+	ChirpID  uuid.NullUUID
+	EventKey string
+}
+
+// Returns no rows if the user has turned the type off or was already
+// notified about the event.
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, createNotification,
+		arg.UserID,
+		arg.Type,
+		arg.ActorID,
+		arg.ChirpID,
+		arg.EventKey,
+	)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Type,
+		&i.ActorID,
+		&i.ChirpID,
+		&i.ReadAt,
+		&i.EventKey,
+	)
+	return i, err
+}
+
+const getNotificationPreferences = `-- name: GetNotificationPreferences :many
+SELECT user_id, type, enabled FROM notification_preferences
+WHERE user_id = $1
+`
+
+func (q *Queries) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, getNotificationPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []NotificationPreference
+	for rows.Next() {
+		var i NotificationPreference
+		if err := rows.Scan(&i.UserID, &i.Type, &i.Enabled); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotifications = `-- name: GetNotifications :many
+SELECT id, created_at, user_id, type, actor_id, chirp_id, read_at, event_key FROM notifications
+WHERE notifications.user_id = $1
+AND (
+  $2::UUID IS NULL
+  OR (notifications.created_at, notifications.id) < (
+    SELECT cursor.created_at, cursor.id FROM notifications AS cursor
+    WHERE cursor.id = $2::UUID
+  )
+)
+AND (NOT $3::BOOLEAN OR notifications.read_at IS NULL)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT $4
+`
+
+type GetNotificationsParams struct {
+	UserID     uuid.UUID
+	Before     uuid.NullUUID
+	UnreadOnly bool
+	MaxResults int32
+}
+
+func (q *Queries) GetNotifications(ctx context.Context, arg GetNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, getNotifications,
+		arg.UserID,
+		arg.Before,
+		arg.UnreadOnly,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Type,
+			&i.ActorID,
+			&i.ChirpID,
+			&i.ReadAt,
+			&i.EventKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL
+`
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markAllNotificationsRead, userID)
+	return err
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+AND user_id = $2
+`
+
+type MarkNotificationReadParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markNotificationRead, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setNotificationPreference = `-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE
+SET enabled = EXCLUDED.enabled
+`
+
+type SetNotificationPreferenceParams struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+func (q *Queries) SetNotificationPreference(ctx context.Context, arg SetNotificationPreferenceParams) error {
+	_, err := q.db.ExecContext(ctx, setNotificationPreference, arg.UserID, arg.Type, arg.Enabled)
+	return err
+}
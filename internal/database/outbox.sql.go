@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: outbox.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+SELECT id, created_at, event_type, payload, attempts, next_attempt_at, last_error, processed_at FROM outbox
+WHERE processed_at IS NULL
+AND next_attempt_at <= NOW()
+AND attempts < $1::INTEGER
+ORDER BY created_at ASC
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimOutboxEventsParams struct {
+	MaxAttempts int32
+	MaxResults  int32
+}
+
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error) {
+	rows, err := q.db.QueryContext(ctx, claimOutboxEvents, arg.MaxAttempts, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Outbox
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.EventType,
+			&i.Payload,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.ProcessedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (id, created_at, event_type, payload, next_attempt_at)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  NOW()
+)
+`
+
+type CreateOutboxEventParams struct {
+	EventType string
+	Payload   json.RawMessage
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	_, err := q.db.ExecContext(ctx, createOutboxEvent, arg.EventType, arg.Payload)
+	return err
+}
+
+const markOutboxEventFailed = `-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+WHERE id = $1
+`
+
+type MarkOutboxEventFailedParams struct {
+	ID            uuid.UUID
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+}
+
+func (q *Queries) MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventFailed, arg.ID, arg.NextAttemptAt, arg.LastError)
+	return err
+}
+
+const markOutboxEventProcessed = `-- name: MarkOutboxEventProcessed :exec
+UPDATE outbox
+SET processed_at = NOW(), attempts = attempts + 1, last_error = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkOutboxEventProcessed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventProcessed, id)
+	return err
+}
+
+const notifyOutbox = `-- name: NotifyOutbox :exec
+SELECT pg_notify('outbox', '')
+`
+
+// Wakes the relay. Postgres holds the notification until the transaction
+// commits, and drops it if it rolls back.
+func (q *Queries) NotifyOutbox(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, notifyOutbox)
+	return err
+}
+
+const purgeProcessedOutboxEvents = `-- name: PurgeProcessedOutboxEvents :execrows
+DELETE FROM outbox
+WHERE processed_at < $1
+`
+
+func (q *Queries) PurgeProcessedOutboxEvents(ctx context.Context, processedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeProcessedOutboxEvents, processedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,162 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: pins.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const countPinnedChirps = `-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+JOIN chirps ON chirps.id = pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+`
+
+func (q *Queries) CountPinnedChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPinnedChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getPin = `-- name: GetPin :one
+SELECT user_id, chirp_id, position, created_at FROM pinned_chirps
+WHERE user_id = $1
+AND chirp_id = $2
+`
+
+type GetPinParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) GetPin(ctx context.Context, arg GetPinParams) (PinnedChirp, error) {
+	row := q.db.QueryRowContext(ctx, getPin, arg.UserID, arg.ChirpID)
+	var i PinnedChirp
+	err := row.Scan(
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPinnedChirps = `-- name: GetPinnedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.like_count, chirps.rechirp_of_id, chirps.quoted_chirp_id, chirps.rechirp_count, chirps.quote_count, chirps.deleted_at, chirps.reply_to_id, chirps.hidden_at, chirps.visibility FROM chirps
+JOIN pinned_chirps ON pinned_chirps.chirp_id = chirps.id
+WHERE pinned_chirps.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY pinned_chirps.position ASC, pinned_chirps.created_at ASC
+`
+
+func (q *Queries) GetPinnedChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPinnedChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuotedChirpID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+			&i.DeletedAt,
+			&i.ReplyToID,
+			&i.HiddenAt,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :one
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+SELECT $1::UUID, $2::UUID, COALESCE(MAX(position) + 1, 0), NOW()
+FROM pinned_chirps
+WHERE user_id = $1::UUID
+ON CONFLICT DO NOTHING
+RETURNING user_id, chirp_id, position, created_at
+`
+
+type PinChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+// New pins go after the existing ones. Returns no rows if the chirp is
+// already pinned.
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) (PinnedChirp, error) {
+	row := q.db.QueryRowContext(ctx, pinChirp, arg.UserID, arg.ChirpID)
+	var i PinnedChirp
+	err := row.Scan(
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const setPinPosition = `-- name: SetPinPosition :execrows
+UPDATE pinned_chirps
+SET position = $3
+WHERE user_id = $1
+AND chirp_id = $2
+`
+
+type SetPinPositionParams struct {
+	UserID   uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+}
+
+func (q *Queries) SetPinPosition(ctx context.Context, arg SetPinPositionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setPinPosition, arg.UserID, arg.ChirpID, arg.Position)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unpinChirp = `-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1
+AND chirp_id = $2
+`
+
+type UnpinChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) error {
+	_, err := q.db.ExecContext(ctx, unpinChirp, arg.UserID, arg.ChirpID)
+	return err
+}
@@ -0,0 +1,238 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: polls.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createPoll = `-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, closes_at)
+VALUES ($1, $2)
+RETURNING chirp_id, closes_at
+`
+
+type CreatePollParams struct {
+	ChirpID  uuid.UUID
+	ClosesAt time.Time
+}
+
+func (q *Queries) CreatePoll(ctx context.Context, arg CreatePollParams) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, createPoll, arg.ChirpID, arg.ClosesAt)
+	var i Poll
+	err := row.Scan(&i.ChirpID, &i.ClosesAt)
+	return i, err
+}
+
+const createPollOption = `-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES ($1, $2, $3)
+`
+
+type CreatePollOptionParams struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+func (q *Queries) CreatePollOption(ctx context.Context, arg CreatePollOptionParams) error {
+	_, err := q.db.ExecContext(ctx, createPollOption, arg.ChirpID, arg.Position, arg.Text)
+	return err
+}
+
+const getPoll = `-- name: GetPoll :one
+SELECT chirp_id, closes_at FROM polls
+WHERE chirp_id = $1
+`
+
+func (q *Queries) GetPoll(ctx context.Context, chirpID uuid.UUID) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, getPoll, chirpID)
+	var i Poll
+	err := row.Scan(&i.ChirpID, &i.ClosesAt)
+	return i, err
+}
+
+const getPollOptionsByChirpIDs = `-- name: GetPollOptionsByChirpIDs :many
+SELECT chirp_id, position, text, vote_count FROM poll_options
+WHERE chirp_id = ANY($1::UUID[])
+ORDER BY chirp_id, position
+`
+
+func (q *Queries) GetPollOptionsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]PollOption, error) {
+	rows, err := q.db.QueryContext(ctx, getPollOptionsByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollOption
+	for rows.Next() {
+		var i PollOption
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Position,
+			&i.Text,
+			&i.VoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollVote = `-- name: GetPollVote :one
+SELECT chirp_id, user_id, position, created_at FROM poll_votes
+WHERE chirp_id = $1
+AND user_id = $2
+`
+
+type GetPollVoteParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) GetPollVote(ctx context.Context, arg GetPollVoteParams) (PollVote, error) {
+	row := q.db.QueryRowContext(ctx, getPollVote, arg.ChirpID, arg.UserID)
+	var i PollVote
+	err := row.Scan(
+		&i.ChirpID,
+		&i.UserID,
+		&i.Position,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPollVotesByUser = `-- name: GetPollVotesByUser :many
+SELECT chirp_id, user_id, position, created_at FROM poll_votes
+WHERE user_id = $1
+AND chirp_id = ANY($2::UUID[])
+`
+
+type GetPollVotesByUserParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetPollVotesByUser(ctx context.Context, arg GetPollVotesByUserParams) ([]PollVote, error) {
+	rows, err := q.db.QueryContext(ctx, getPollVotesByUser, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollVote
+	for rows.Next() {
+		var i PollVote
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.UserID,
+			&i.Position,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPollsByChirpIDs = `-- name: GetPollsByChirpIDs :many
+SELECT chirp_id, closes_at FROM polls
+WHERE chirp_id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetPollsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]Poll, error) {
+	rows, err := q.db.QueryContext(ctx, getPollsByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Poll
+	for rows.Next() {
+		var i Poll
+		if err := rows.Scan(&i.ChirpID, &i.ClosesAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removePollVotesOfUsersPastGracePeriod = `-- name: RemovePollVotesOfUsersPastGracePeriod :exec
+UPDATE poll_options
+SET vote_count = poll_options.vote_count - purged.votes
+FROM (
+  SELECT poll_votes.chirp_id, poll_votes.position, COUNT(*) AS votes
+  FROM poll_votes
+  JOIN users ON users.id = poll_votes.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  GROUP BY poll_votes.chirp_id, poll_votes.position
+) AS purged
+WHERE poll_options.chirp_id = purged.chirp_id
+AND poll_options.position = purged.position
+`
+
+func (q *Queries) RemovePollVotesOfUsersPastGracePeriod(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, removePollVotesOfUsersPastGracePeriod)
+	return err
+}
+
+const votePoll = `-- name: VotePoll :execrows
+WITH inserted AS (
+  INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+  SELECT polls.chirp_id, $1::UUID, $2::INTEGER, NOW()
+  FROM polls
+  WHERE polls.chirp_id = $3::UUID
+  AND polls.closes_at > NOW()
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id, position
+)
+UPDATE poll_options
+SET vote_count = vote_count + 1
+FROM inserted
+WHERE poll_options.chirp_id = inserted.chirp_id
+AND poll_options.position = inserted.position
+`
+
+type VotePollParams struct {
+	UserID   uuid.UUID
+	Position int32
+	ChirpID  uuid.UUID
+}
+
+// Affects no rows if the user has already voted or the poll has closed.
+// The vote and the count change together, so concurrent votes can't lose
+// an update.
+func (q *Queries) VotePoll(ctx context.Context, arg VotePollParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, votePoll, arg.UserID, arg.Position, arg.ChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
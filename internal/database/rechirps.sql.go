@@ -0,0 +1,177 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: rechirps.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  '',
+  $1,
+  $2::UUID
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility
+`
+
+type CreateRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.UUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, like_count, rechirp_of_id, quoted_chirp_id, rechirp_count, quote_count, deleted_at, reply_to_id, hidden_at, visibility FROM chirps
+WHERE user_id = $1
+AND rechirp_of_id = $2::UUID
+`
+
+type GetRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.UUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuotedChirpID,
+		&i.RechirpCount,
+		&i.QuoteCount,
+		&i.DeletedAt,
+		&i.ReplyToID,
+		&i.HiddenAt,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getRechirpedChirpIDs = `-- name: GetRechirpedChirpIDs :many
+SELECT rechirp_of_id::UUID FROM chirps
+WHERE user_id = $1
+AND rechirp_of_id = ANY($2::UUID[])
+`
+
+type GetRechirpedChirpIDsParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetRechirpedChirpIDs(ctx context.Context, arg GetRechirpedChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpedChirpIDs, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var rechirp_of_id uuid.UUID
+		if err := rows.Scan(&rechirp_of_id); err != nil {
+			return nil, err
+		}
+		items = append(items, rechirp_of_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeSharesOfUsersPastGracePeriod = `-- name: RemoveSharesOfUsersPastGracePeriod :exec
+UPDATE chirps
+SET rechirp_count = chirps.rechirp_count - purged.rechirps,
+  quote_count = chirps.quote_count - purged.quotes
+FROM (
+  SELECT COALESCE(shares.rechirp_of_id, shares.quoted_chirp_id) AS chirp_id,
+    COUNT(shares.rechirp_of_id) AS rechirps,
+    COUNT(shares.quoted_chirp_id) AS quotes
+  FROM chirps AS shares
+  JOIN users ON users.id = shares.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  AND (shares.rechirp_of_id IS NOT NULL OR shares.quoted_chirp_id IS NOT NULL)
+  AND shares.deleted_at IS NULL
+  GROUP BY 1
+) AS purged
+WHERE chirps.id = purged.chirp_id
+`
+
+func (q *Queries) RemoveSharesOfUsersPastGracePeriod(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, removeSharesOfUsersPastGracePeriod)
+	return err
+}
+
+const updateQuoteCount = `-- name: UpdateQuoteCount :exec
+UPDATE chirps
+SET quote_count = quote_count + $1
+WHERE id = $2
+`
+
+type UpdateQuoteCountParams struct {
+	Delta int32
+	ID    uuid.UUID
+}
+
+func (q *Queries) UpdateQuoteCount(ctx context.Context, arg UpdateQuoteCountParams) error {
+	_, err := q.db.ExecContext(ctx, updateQuoteCount, arg.Delta, arg.ID)
+	return err
+}
+
+const updateRechirpCount = `-- name: UpdateRechirpCount :exec
+UPDATE chirps
+SET rechirp_count = rechirp_count + $1
+WHERE id = $2
+`
+
+type UpdateRechirpCountParams struct {
+	Delta int32
+	ID    uuid.UUID
+}
+
+func (q *Queries) UpdateRechirpCount(ctx context.Context, arg UpdateRechirpCountParams) error {
+	_, err := q.db.ExecContext(ctx, updateRechirpCount, arg.Delta, arg.ID)
+	return err
+}
@@ -0,0 +1,563 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: webhooks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = $1, leased_until = $1
+WHERE id IN (
+  SELECT d.id FROM webhook_deliveries d
+  JOIN webhook_subscriptions s ON s.id = d.subscription_id
+  WHERE d.status = 'pending'
+  AND d.next_attempt_at <= NOW()
+  AND s.enabled
+  ORDER BY d.next_attempt_at ASC
+  LIMIT $2
+  FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING id, created_at, subscription_id, event_type, payload, status, attempts, next_attempt_at, delivered_at, event_key, leased_until
+`
+
+type ClaimDueWebhookDeliveriesParams struct {
+	LeaseUntil time.Time
+	MaxResults int32
+}
+
+// Leases due deliveries by pushing next_attempt_at past the time it takes
+// to send them, so a crashed worker's deliveries are picked up again.
+// leased_until marks them as being sent until the attempt is recorded.
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueWebhookDeliveries, arg.LeaseUntil, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.SubscriptionID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.DeliveredAt,
+			&i.EventKey,
+			&i.LeasedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhookDeliveryAttempt = `-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, created_at, delivery_id, status_code, error, duration_ms)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+`
+
+type CreateWebhookDeliveryAttemptParams struct {
+	DeliveryID uuid.UUID
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+	DurationMs int32
+}
+
+func (q *Queries) CreateWebhookDeliveryAttempt(ctx context.Context, arg CreateWebhookDeliveryAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, createWebhookDeliveryAttempt,
+		arg.DeliveryID,
+		arg.StatusCode,
+		arg.Error,
+		arg.DurationMs,
+	)
+	return err
+}
+
+const createWebhookSubscription = `-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, user_id, url, secret, event_types)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at
+`
+
+type CreateWebhookSubscriptionParams struct {
+	UserID     uuid.NullUUID
+	Url        string
+	Secret     string
+	EventTypes []string
+}
+
+func (q *Queries) CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookSubscription,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		pq.Array(arg.EventTypes),
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.EventTypes),
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const deleteWebhookSubscription = `-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1
+AND user_id IS NOT DISTINCT FROM $2
+`
+
+type DeleteWebhookSubscriptionParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.NullUUID
+}
+
+func (q *Queries) DeleteWebhookSubscription(ctx context.Context, arg DeleteWebhookSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhookSubscription, arg.ID, arg.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const enqueueWebhookDeliveries = `-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (id, created_at, subscription_id, event_type, payload, next_attempt_at, event_key)
+SELECT gen_random_uuid(), NOW(), id, $1, $2, NOW(), $3::TEXT
+FROM webhook_subscriptions
+WHERE enabled
+AND $1 = ANY(event_types)
+AND (user_id IS NULL OR user_id = $4::UUID)
+ON CONFLICT (subscription_id, event_key) DO NOTHING
+`
+
+type EnqueueWebhookDeliveriesParams struct {
+	EventType string
+	Payload   json.RawMessage
+	EventKey  string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enqueueWebhookDeliveries,
+		arg.EventType,
+		arg.Payload,
+		arg.EventKey,
+		arg.UserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT id, created_at, subscription_id, event_type, payload, status, attempts, next_attempt_at, delivered_at, event_key, leased_until FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetWebhookDeliveriesParams struct {
+	SubscriptionID uuid.UUID
+	Limit          int32
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveries, arg.SubscriptionID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.SubscriptionID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.DeliveredAt,
+			&i.EventKey,
+			&i.LeasedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, created_at, subscription_id, event_type, payload, status, attempts, next_attempt_at, delivered_at, event_key, leased_until FROM webhook_deliveries
+WHERE id = $1
+AND subscription_id = $2
+`
+
+type GetWebhookDeliveryParams struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+}
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDelivery, arg.ID, arg.SubscriptionID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.SubscriptionID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.DeliveredAt,
+		&i.EventKey,
+		&i.LeasedUntil,
+	)
+	return i, err
+}
+
+const getWebhookDeliveryAttempts = `-- name: GetWebhookDeliveryAttempts :many
+SELECT id, created_at, delivery_id, status_code, error, duration_ms FROM webhook_delivery_attempts
+WHERE delivery_id = ANY($1::UUID[])
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetWebhookDeliveryAttempts(ctx context.Context, deliveryIds []uuid.UUID) ([]WebhookDeliveryAttempt, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveryAttempts, pq.Array(deliveryIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDeliveryAttempt
+	for rows.Next() {
+		var i WebhookDeliveryAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.DeliveryID,
+			&i.StatusCode,
+			&i.Error,
+			&i.DurationMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookSubscription = `-- name: GetWebhookSubscription :one
+SELECT id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at FROM webhook_subscriptions
+WHERE id = $1
+AND user_id IS NOT DISTINCT FROM $2
+`
+
+type GetWebhookSubscriptionParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.NullUUID
+}
+
+func (q *Queries) GetWebhookSubscription(ctx context.Context, arg GetWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookSubscription, arg.ID, arg.OwnerID)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.EventTypes),
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscriptionByID = `-- name: GetWebhookSubscriptionByID :one
+SELECT id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at FROM webhook_subscriptions
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookSubscriptionByID(ctx context.Context, id uuid.UUID) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookSubscriptionByID, id)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.EventTypes),
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const getWebhookSubscriptions = `-- name: GetWebhookSubscriptions :many
+SELECT id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at FROM webhook_subscriptions
+WHERE user_id IS NOT DISTINCT FROM $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetWebhookSubscriptions(ctx context.Context, ownerID uuid.NullUUID) ([]WebhookSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookSubscriptions, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookSubscription
+	for rows.Next() {
+		var i WebhookSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			pq.Array(&i.EventTypes),
+			&i.Enabled,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookDeliveryFailed = `-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, leased_until = NULL
+WHERE id = $1
+`
+
+type MarkWebhookDeliveryFailedParams struct {
+	ID            uuid.UUID
+	Status        string
+	NextAttemptAt time.Time
+}
+
+// Records a failed attempt. The delivery is retried at next_attempt_at
+// unless status gives up on it.
+func (q *Queries) MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markWebhookDeliveryFailed, arg.ID, arg.Status, arg.NextAttemptAt)
+	return err
+}
+
+const markWebhookDeliverySucceeded = `-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, delivered_at = NOW(), leased_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookDeliverySucceeded(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markWebhookDeliverySucceeded, id)
+	return err
+}
+
+const recordWebhookFailure = `-- name: RecordWebhookFailure :one
+UPDATE webhook_subscriptions
+SET consecutive_failures = consecutive_failures + 1,
+  enabled = enabled AND consecutive_failures + 1 < $1::INTEGER,
+  disabled_at = CASE
+    WHEN enabled AND consecutive_failures + 1 >= $1::INTEGER THEN NOW()
+    ELSE disabled_at
+  END
+WHERE id = $2
+RETURNING id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at
+`
+
+type RecordWebhookFailureParams struct {
+	MaxFailures int32
+	ID          uuid.UUID
+}
+
+// Counts a failed attempt against the subscription, disabling it once
+// @max_failures attempts in a row have failed.
+func (q *Queries) RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, recordWebhookFailure, arg.MaxFailures, arg.ID)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.EventTypes),
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
+
+const recordWebhookSuccess = `-- name: RecordWebhookSuccess :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = 0
+WHERE id = $1
+`
+
+func (q *Queries) RecordWebhookSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordWebhookSuccess, id)
+	return err
+}
+
+const redeliverWebhookDelivery = `-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+WHERE id = $1
+AND subscription_id = $2
+AND (leased_until IS NULL OR leased_until <= NOW())
+RETURNING id, created_at, subscription_id, event_type, payload, status, attempts, next_attempt_at, delivered_at, event_key, leased_until
+`
+
+type RedeliverWebhookDeliveryParams struct {
+	ID             uuid.UUID
+	SubscriptionID uuid.UUID
+}
+
+// Returns no rows while a worker holds the delivery, so it isn't sent
+// twice at once.
+func (q *Queries) RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, redeliverWebhookDelivery, arg.ID, arg.SubscriptionID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.SubscriptionID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.DeliveredAt,
+		&i.EventKey,
+		&i.LeasedUntil,
+	)
+	return i, err
+}
+
+const updateWebhookSubscription = `-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = $1,
+  event_types = $2,
+  enabled = $3,
+  consecutive_failures = CASE WHEN $3::BOOLEAN THEN 0 ELSE consecutive_failures END,
+  disabled_at = CASE
+    WHEN $3::BOOLEAN THEN NULL
+    ELSE COALESCE(disabled_at, NOW())
+  END,
+  updated_at = NOW()
+WHERE id = $4
+AND user_id IS NOT DISTINCT FROM $5
+RETURNING id, created_at, updated_at, user_id, url, secret, event_types, enabled, consecutive_failures, disabled_at
+`
+
+type UpdateWebhookSubscriptionParams struct {
+	Url        string
+	EventTypes []string
+	Enabled    bool
+	ID         uuid.UUID
+	OwnerID    uuid.NullUUID
+}
+
+func (q *Queries) UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookSubscription,
+		arg.Url,
+		pq.Array(arg.EventTypes),
+		arg.Enabled,
+		arg.ID,
+		arg.OwnerID,
+	)
+	var i WebhookSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.EventTypes),
+		&i.Enabled,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+	)
+	return i, err
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Event is something that happened in the domain, such as a chirp being
+// created. The payload is JSON so events can be stored and replayed.
+type Event struct {
+	// Key identifies the event. An event can be published more than once,
+	// always with the same key, so handlers use it to skip duplicates.
+	Key     string
+	Type    string
+	Payload json.RawMessage
+}
+
+// New builds an event, encoding payload as JSON.
+func New(eventType string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("couldn't encode %s event: %w", eventType, err)
+	}
+	return Event{Type: eventType, Payload: data}, nil
+}
+
+// Decode unmarshals the event's payload into v.
+func (e Event) Decode(v any) error {
+	err := json.Unmarshal(e.Payload, v)
+	if err != nil {
+		return fmt.Errorf("couldn't decode %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+type Handler func(ctx context.Context, e Event) error
+
+// Bus delivers events to the handlers subscribed to their type, in the
+// order they subscribed.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+func (b *Bus) Subscribe(eventType string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], h)
+}
+
+// Publish runs every handler for the event synchronously. A failing
+// handler doesn't stop the others; their errors are returned together.
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[e.Type]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		err := h(ctx, e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s handler: %w", e.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
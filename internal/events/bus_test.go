@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		eventType string
+		wantCalls []string
+		wantErr   error
+	}{
+		{
+			name:      "Handlers run in subscription order",
+			eventType: "a",
+			wantCalls: []string{"a1", "a2"},
+		},
+		{
+			name:      "A failing handler doesn't stop the rest",
+			eventType: "b",
+			wantCalls: []string{"b1", "b2"},
+			wantErr:   errBoom,
+		},
+		{
+			name:      "No handlers",
+			eventType: "c",
+			wantCalls: []string{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := []string{}
+			record := func(name string, err error) Handler {
+				return func(ctx context.Context, e Event) error {
+					calls = append(calls, name)
+					return err
+				}
+			}
+
+			bus := NewBus()
+			bus.Subscribe("a", record("a1", nil))
+			bus.Subscribe("a", record("a2", nil))
+			bus.Subscribe("b", record("b1", errBoom))
+			bus.Subscribe("b", record("b2", nil))
+
+			err := bus.Publish(context.Background(), Event{Type: tc.eventType})
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("Test %v - '%s': FAIL: expected error %v, got %v", i, tc.name, tc.wantErr, err)
+			}
+			if len(calls) != len(tc.wantCalls) {
+				t.Fatalf("Test %v - '%s': FAIL: expected calls %v, got %v", i, tc.name, tc.wantCalls, calls)
+			}
+			for j := range calls {
+				if calls[j] != tc.wantCalls[j] {
+					t.Errorf("Test %v - '%s': FAIL: expected calls %v, got %v", i, tc.name, tc.wantCalls, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	e, err := New("greeting", payload{Name: "chirpy"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	got := payload{}
+	err = e.Decode(&got)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Name != "chirpy" {
+		t.Errorf("expected %q, got %q", "chirpy", got.Name)
+	}
+}
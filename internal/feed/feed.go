@@ -0,0 +1,60 @@
+// Package feed decides which users' content a viewer gets to see, given
+// who they have blocked or muted and who has blocked them.
+package feed
+
+import "github.com/google/uuid"
+
+// Kind is how a viewer relates to another user.
+type Kind string
+
+const (
+	// Blocking means the viewer has blocked the other user.
+	Blocking Kind = "blocking"
+	// BlockedBy means the other user has blocked the viewer.
+	BlockedBy Kind = "blocked_by"
+	// Muting means the viewer has muted the other user.
+	Muting Kind = "muting"
+)
+
+// Relations holds a viewer's blocks and mutes. The zero value has none, which
+// is also what an anonymous viewer gets.
+type Relations struct {
+	blocked map[uuid.UUID]bool
+	muted   map[uuid.UUID]bool
+}
+
+// Add records that the viewer relates to userID as kind. Unknown kinds are
+// ignored.
+func (r *Relations) Add(kind Kind, userID uuid.UUID) {
+	switch kind {
+	case Blocking, BlockedBy:
+		if r.blocked == nil {
+			r.blocked = map[uuid.UUID]bool{}
+		}
+		r.blocked[userID] = true
+	case Muting:
+		if r.muted == nil {
+			r.muted = map[uuid.UUID]bool{}
+		}
+		r.muted[userID] = true
+	}
+}
+
+// Blocked reports whether a block in either direction stands between the
+// viewer and userID. Blocked users can't follow, reply to or mention each
+// other, and neither sees the other's chirps.
+func (r Relations) Blocked(userID uuid.UUID) bool {
+	return r.blocked[userID]
+}
+
+// Hides reports whether content involving any of userIDs, such as a chirp
+// and the chirp it shares, should be left out of the viewer's views. A mute
+// only hides the muted user from the viewer, never the other way round.
+func (r Relations) Hides(userIDs ...uuid.UUID) bool {
+	for _, id := range userIDs {
+		if r.blocked[id] || r.muted[id] {
+			return true
+		}
+	}
+	return false
+}
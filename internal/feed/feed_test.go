@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRelations(t *testing.T) {
+	blocked := uuid.New()
+	blocker := uuid.New()
+	muted := uuid.New()
+	stranger := uuid.New()
+
+	relations := Relations{}
+	relations.Add(Blocking, blocked)
+	relations.Add(BlockedBy, blocker)
+	relations.Add(Muting, muted)
+	relations.Add(Kind("unknown"), stranger)
+
+	tests := []struct {
+		name        string
+		relations   Relations
+		userIDs     []uuid.UUID
+		wantBlocked bool
+		wantHidden  bool
+	}{
+		{
+			name:        "User the viewer blocked",
+			relations:   relations,
+			userIDs:     []uuid.UUID{blocked},
+			wantBlocked: true,
+			wantHidden:  true,
+		},
+		{
+			name:        "User who blocked the viewer",
+			relations:   relations,
+			userIDs:     []uuid.UUID{blocker},
+			wantBlocked: true,
+			wantHidden:  true,
+		},
+		{
+			name:        "Muted user is hidden but not blocked",
+			relations:   relations,
+			userIDs:     []uuid.UUID{muted},
+			wantBlocked: false,
+			wantHidden:  true,
+		},
+		{
+			name:        "Unrelated user",
+			relations:   relations,
+			userIDs:     []uuid.UUID{stranger},
+			wantBlocked: false,
+			wantHidden:  false,
+		},
+		{
+			name:        "Sharing a hidden user's chirp hides the share",
+			relations:   relations,
+			userIDs:     []uuid.UUID{stranger, muted},
+			wantBlocked: false,
+			wantHidden:  true,
+		},
+		{
+			name:        "Anonymous viewer sees everyone",
+			relations:   Relations{},
+			userIDs:     []uuid.UUID{blocked},
+			wantBlocked: false,
+			wantHidden:  false,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.relations.Blocked(tc.userIDs[0]); got != tc.wantBlocked {
+				t.Errorf("Test %v - '%s': FAIL: expected blocked %v, got %v", i, tc.name, tc.wantBlocked, got)
+			}
+			if got := tc.relations.Hides(tc.userIDs...); got != tc.wantHidden {
+				t.Errorf("Test %v - '%s': FAIL: expected hidden %v, got %v", i, tc.name, tc.wantHidden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package feed
+
+import "github.com/google/uuid"
+
+// Visibility is who a chirp is for.
+type Visibility string
+
+const (
+	// Public chirps are for anyone, unless their author is protected.
+	Public Visibility = "public"
+	// Followers chirps are for the author's followers.
+	Followers Visibility = "followers"
+	// Mentioned chirps are for the users they mention.
+	Mentioned Visibility = "mentioned"
+)
+
+func (v Visibility) Valid() bool {
+	return v == Public || v == Followers || v == Mentioned
+}
+
+// Audience is what decides who can see a chirp.
+type Audience struct {
+	AuthorID   uuid.UUID
+	Visibility Visibility
+	// Protected is whether the author's account is protected, which limits
+	// their public chirps to followers.
+	Protected bool
+	// Mentioned lists the users the chirp mentions.
+	Mentioned []uuid.UUID
+	// Hidden is whether moderators have hidden the chirp, which leaves it
+	// to its author alone.
+	Hidden bool
+}
+
+// Viewer is who is asking to see a chirp. The zero value is an anonymous
+// viewer.
+type Viewer struct {
+	ID        uuid.UUID
+	Following map[uuid.UUID]bool
+}
+
+// CanView reports whether viewer may see a chirp with audience a. Authors
+// always see their own chirps.
+func (v Viewer) CanView(a Audience) bool {
+	if v.ID != uuid.Nil && v.ID == a.AuthorID {
+		return true
+	}
+	if a.Hidden {
+		return false
+	}
+
+	switch a.Visibility {
+	case Public:
+		return !a.Protected || v.Following[a.AuthorID]
+	case Followers:
+		return v.Following[a.AuthorID]
+	case Mentioned:
+		if v.ID == uuid.Nil {
+			return false
+		}
+		for _, id := range a.Mentioned {
+			if id == v.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
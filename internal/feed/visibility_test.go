@@ -0,0 +1,88 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCanView(t *testing.T) {
+	author := uuid.New()
+	follower := uuid.New()
+	mentioned := uuid.New()
+	stranger := uuid.New()
+
+	viewers := map[string]Viewer{
+		"anonymous": {},
+		"author":    {ID: author},
+		"follower":  {ID: follower, Following: map[uuid.UUID]bool{author: true}},
+		"mentioned": {ID: mentioned},
+		"stranger":  {ID: stranger, Following: map[uuid.UUID]bool{uuid.New(): true}},
+	}
+
+	tests := []struct {
+		name     string
+		audience Audience
+		want     map[string]bool
+	}{
+		{
+			name:     "Public chirp",
+			audience: Audience{AuthorID: author, Visibility: Public},
+			want: map[string]bool{
+				"anonymous": true, "author": true, "follower": true, "mentioned": true, "stranger": true,
+			},
+		},
+		{
+			name:     "Public chirp from a protected account",
+			audience: Audience{AuthorID: author, Visibility: Public, Protected: true},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": true, "mentioned": false, "stranger": false,
+			},
+		},
+		{
+			name:     "Followers-only chirp",
+			audience: Audience{AuthorID: author, Visibility: Followers},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": true, "mentioned": false, "stranger": false,
+			},
+		},
+		{
+			name:     "Mentioned-only chirp",
+			audience: Audience{AuthorID: author, Visibility: Mentioned, Mentioned: []uuid.UUID{mentioned}},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": false, "mentioned": true, "stranger": false,
+			},
+		},
+		{
+			name:     "Mentioned-only chirp from a protected account",
+			audience: Audience{AuthorID: author, Visibility: Mentioned, Protected: true, Mentioned: []uuid.UUID{mentioned}},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": false, "mentioned": true, "stranger": false,
+			},
+		},
+		{
+			name:     "Chirp hidden by moderators",
+			audience: Audience{AuthorID: author, Visibility: Public, Hidden: true},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": false, "mentioned": false, "stranger": false,
+			},
+		},
+		{
+			name:     "Unknown visibility is hidden from everyone but the author",
+			audience: Audience{AuthorID: author, Visibility: "secret"},
+			want: map[string]bool{
+				"anonymous": false, "author": true, "follower": false, "mentioned": false, "stranger": false,
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, viewer := range viewers {
+				if got := viewer.CanView(tc.audience); got != tc.want[name] {
+					t.Errorf("Test %v - '%s': FAIL: expected %s to see it: %v, got %v", i, tc.name, name, tc.want[name], got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"github.com/chonginator/chirpy/internal/moderation"
+)
+
+// Action is what happens to a chirp that matches one of a viewer's muted
+// words.
+type Action string
+
+const (
+	// ActionHide leaves the chirp out of the viewer's listings.
+	ActionHide Action = "hide"
+	// ActionCollapse keeps the chirp but marks it so clients can show it
+	// folded away.
+	ActionCollapse Action = "collapse"
+)
+
+func (a Action) Valid() bool {
+	return a == ActionHide || a == ActionCollapse
+}
+
+// TermKind says how a muted term is matched.
+type TermKind string
+
+const (
+	// KindWord matches a word or phrase the way the moderation word list
+	// does, so "Spoiler!" and "spöiler" both match "spoiler".
+	KindWord TermKind = "word"
+	// KindHashtag matches a hashtag, with or without its leading #.
+	KindHashtag TermKind = "hashtag"
+)
+
+func (k TermKind) Valid() bool {
+	return k == KindWord || k == KindHashtag
+}
+
+// MutedTerm is one of a viewer's muted words, phrases or hashtags.
+type MutedTerm struct {
+	Kind    TermKind
+	Pattern string
+	Action  Action
+}
+
+// WordFilter checks chirps against a viewer's muted terms.
+type WordFilter struct {
+	phrases  []mutedPhrase
+	hashtags map[string]mutedTerm
+}
+
+type mutedPhrase struct {
+	words moderation.Phrase
+	mutedTerm
+}
+
+type mutedTerm struct {
+	pattern string
+	action  Action
+}
+
+// NewWordFilter compiles terms into a filter. Terms with nothing to match,
+// such as a word that is all punctuation, are skipped.
+func NewWordFilter(terms []MutedTerm) *WordFilter {
+	f := &WordFilter{hashtags: map[string]mutedTerm{}}
+	for _, term := range terms {
+		muted := mutedTerm{pattern: term.Pattern, action: term.Action}
+		switch term.Kind {
+		case KindWord:
+			words := moderation.NewPhrase(term.Pattern)
+			if len(words) > 0 {
+				f.phrases = append(f.phrases, mutedPhrase{words: words, mutedTerm: muted})
+			}
+		case KindHashtag:
+			tag := chirptext.NormalizeTag(term.Pattern)
+			if tag == "" {
+				continue
+			}
+			if existing, ok := f.hashtags[tag]; !ok || existing.action != ActionHide {
+				f.hashtags[tag] = muted
+			}
+		}
+	}
+	return f
+}
+
+// Match returns what to do with a chirp and the patterns that matched it,
+// checking each of texts: the chirp's body and those of any chirps it
+// shares. Hiding wins over collapsing; an empty Action means nothing
+// matched.
+func (f *WordFilter) Match(texts ...string) (Action, []string) {
+	var action Action
+	patterns := []string{}
+	matched := map[string]bool{}
+	add := func(muted mutedTerm) {
+		if matched[muted.pattern] {
+			return
+		}
+		matched[muted.pattern] = true
+		patterns = append(patterns, muted.pattern)
+		if action != ActionHide {
+			action = muted.action
+		}
+	}
+
+	for _, text := range texts {
+		if len(f.phrases) > 0 {
+			tokens := moderation.Tokenize(text)
+			for _, p := range f.phrases {
+				if p.words.In(tokens) {
+					add(p.mutedTerm)
+				}
+			}
+		}
+
+		if len(f.hashtags) > 0 {
+			for _, entity := range chirptext.ParseEntities(text) {
+				if entity.Type != chirptext.EntityHashtag {
+					continue
+				}
+				if muted, ok := f.hashtags[chirptext.NormalizeTag(entity.Text)]; ok {
+					add(muted)
+				}
+			}
+		}
+	}
+
+	return action, patterns
+}
@@ -0,0 +1,84 @@
+package feed
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestWordFilterMatch(t *testing.T) {
+	filter := NewWordFilter([]MutedTerm{
+		{Kind: KindWord, Pattern: "spoiler", Action: ActionCollapse},
+		{Kind: KindWord, Pattern: "season finale", Action: ActionHide},
+		{Kind: KindHashtag, Pattern: "#GoT", Action: ActionHide},
+		{Kind: KindHashtag, Pattern: "football", Action: ActionCollapse},
+		{Kind: KindWord, Pattern: "!!!", Action: ActionHide},
+	})
+
+	tests := []struct {
+		name         string
+		texts        []string
+		wantAction   Action
+		wantPatterns []string
+	}{
+		{
+			name:         "No match",
+			texts:        []string{"Nothing to see here"},
+			wantAction:   "",
+			wantPatterns: []string{},
+		},
+		{
+			name:         "Word is matched like the moderation list",
+			texts:        []string{"Big SPÖILER!"},
+			wantAction:   ActionCollapse,
+			wantPatterns: []string{"spoiler"},
+		},
+		{
+			name:         "Word inside another word doesn't match",
+			texts:        []string{"spoilers everywhere"},
+			wantAction:   "",
+			wantPatterns: []string{},
+		},
+		{
+			name:         "Phrase",
+			texts:        []string{"Did you watch the season\nfinale?"},
+			wantAction:   ActionHide,
+			wantPatterns: []string{"season finale"},
+		},
+		{
+			name:         "Hashtag ignores case",
+			texts:        []string{"Watching #got tonight"},
+			wantAction:   ActionHide,
+			wantPatterns: []string{"#GoT"},
+		},
+		{
+			name:         "Hashtag term doesn't match the bare word",
+			texts:        []string{"I got it"},
+			wantAction:   "",
+			wantPatterns: []string{},
+		},
+		{
+			name:         "Shared chirp is checked too",
+			texts:        []string{"", "Huge spoiler inside"},
+			wantAction:   ActionCollapse,
+			wantPatterns: []string{"spoiler"},
+		},
+		{
+			name:         "Hide wins over collapse",
+			texts:        []string{"#football spoiler: the season finale"},
+			wantAction:   ActionHide,
+			wantPatterns: []string{"spoiler", "season finale", "football"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			action, patterns := filter.Match(tc.texts...)
+			if action != tc.wantAction {
+				t.Errorf("Test %v - '%s': FAIL: expected action %q, got %q", i, tc.name, tc.wantAction, action)
+			}
+			if !slices.Equal(patterns, tc.wantPatterns) {
+				t.Errorf("Test %v - '%s': FAIL: expected patterns %v, got %v", i, tc.name, tc.wantPatterns, patterns)
+			}
+		})
+	}
+}
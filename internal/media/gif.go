@@ -0,0 +1,80 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// MaxFrames and MaxGIFPixels bound an animated GIF. Each frame fits
+	// within the MaxPixels canvas, but together they could still decode to
+	// more than memory allows.
+	MaxFrames    = 500
+	MaxGIFPixels = 4 * MaxPixels
+)
+
+var errMalformedGIF = errors.New("couldn't decode image: malformed GIF")
+
+// checkGIFFrames walks the blocks of a GIF without decoding any pixel
+// data, returning ErrTooLarge if it has more than MaxFrames frames or
+// their areas add up to more than MaxGIFPixels.
+func checkGIFFrames(data []byte) error {
+	// The header and logical screen descriptor, then the optional global
+	// color table.
+	if len(data) < 13 {
+		return errMalformedGIF
+	}
+	pos := skipColorTable(data[10], 13)
+
+	frames, pixels := 0, 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21:
+			// An extension: its label, then data sub-blocks.
+			pos = skipSubBlocks(data, pos+2)
+		case 0x2C:
+			// An image descriptor, then the optional local color table,
+			// the LZW minimum code size and the image data sub-blocks.
+			if pos+10 > len(data) {
+				return errMalformedGIF
+			}
+			width := int(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int(binary.LittleEndian.Uint16(data[pos+7:]))
+			frames++
+			pixels += width * height
+			if frames > MaxFrames || pixels > MaxGIFPixels {
+				return ErrTooLarge
+			}
+			pos = skipColorTable(data[pos+9], pos+10)
+			pos = skipSubBlocks(data, pos+1)
+		case 0x3B:
+			return nil
+		default:
+			return errMalformedGIF
+		}
+	}
+	return errMalformedGIF
+}
+
+// skipColorTable skips the color table a packed field says follows pos.
+func skipColorTable(packed byte, pos int) int {
+	if packed&0x80 == 0 {
+		return pos
+	}
+	return pos + 3*(1<<(packed&0x07+1))
+}
+
+// skipSubBlocks skips the data sub-blocks starting at pos, up to and
+// including the terminating empty block. It returns len(data) if the
+// blocks run past the end of the data.
+func skipSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos
+		}
+		pos += size
+	}
+	return len(data)
+}
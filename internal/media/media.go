@@ -0,0 +1,219 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxDimension and MaxPixels bound the decoded size of an upload, so a
+	// small file can't expand into an image that exhausts memory.
+	MaxDimension = 8192
+	MaxPixels    = 16_000_000
+
+	// ThumbnailSize is the longest side of a thumbnail in pixels.
+	ThumbnailSize = 320
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image dimensions too large")
+)
+
+// Extensions maps the content types Process accepts to the file extension
+// used when storing them.
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// File is an encoded image ready to store.
+type File struct {
+	ContentType string
+	Data        []byte
+}
+
+// Image is the result of processing an upload: the image re-encoded
+// without any metadata, and a thumbnail of it.
+type Image struct {
+	File
+	Width     int
+	Height    int
+	Thumbnail File
+}
+
+// Process checks an uploaded image and re-encodes it. The content type is
+// sniffed from the data rather than trusted from the client. Re-encoding
+// writes only pixel data, which drops EXIF and any other metadata; a JPEG's
+// EXIF orientation is applied to the pixels first so the image still
+// displays the right way up.
+func Process(data []byte) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode image: %w", err)
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	switch contentType {
+	case "image/gif":
+		return processGIF(data)
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode image: %w", err)
+		}
+		return encode(img, contentType, func(buf *bytes.Buffer, img image.Image) error {
+			return png.Encode(buf, img)
+		})
+	default:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode image: %w", err)
+		}
+		if orientation := jpegOrientation(data); orientation > 1 {
+			img = orient(toRGBA(img), orientation)
+		}
+		return encode(img, contentType, func(buf *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(buf, img, &jpeg.Options{Quality: jpegQuality})
+		})
+	}
+}
+
+// encode writes img and its thumbnail in the same format.
+func encode(img image.Image, contentType string, enc func(*bytes.Buffer, image.Image) error) (*Image, error) {
+	var full, thumb bytes.Buffer
+	err := enc(&full, img)
+	if err != nil {
+		return nil, err
+	}
+	err = enc(&thumb, Thumbnail(img, ThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return &Image{
+		File:      File{ContentType: contentType, Data: full.Bytes()},
+		Width:     bounds.Dx(),
+		Height:    bounds.Dy(),
+		Thumbnail: File{ContentType: contentType, Data: thumb.Bytes()},
+	}, nil
+}
+
+// processGIF re-encodes every frame, keeping animation, and thumbnails the
+// first frame as a PNG.
+func processGIF(data []byte) (*Image, error) {
+	err := checkGIFFrames(data)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode image: %w", err)
+	}
+	if len(g.Image) == 0 {
+		return nil, errors.New("couldn't decode image: no frames")
+	}
+
+	var full bytes.Buffer
+	err = gif.EncodeAll(&full, &gif.GIF{
+		Image:           g.Image,
+		Delay:           g.Delay,
+		LoopCount:       g.LoopCount,
+		Disposal:        g.Disposal,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The first frame may cover only part of the canvas.
+	first := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	draw.Draw(first, g.Image[0].Bounds(), g.Image[0], g.Image[0].Bounds().Min, draw.Over)
+
+	var thumb bytes.Buffer
+	err = png.Encode(&thumb, Thumbnail(first, ThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		File:      File{ContentType: "image/gif", Data: full.Bytes()},
+		Width:     g.Config.Width,
+		Height:    g.Config.Height,
+		Thumbnail: File{ContentType: "image/png", Data: thumb.Bytes()},
+	}, nil
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// Thumbnail scales img down so its longest side is at most size pixels,
+// averaging the source pixels that fall in each thumbnail pixel. Images
+// that already fit are returned at their original size.
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+
+	dw, dh := size, size
+	if sw > sh {
+		dh = max(1, sh*size/sw)
+	} else {
+		dw = max(1, sw*size/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, (dy+1)*sh/dh
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, (dx+1)*sw/dw
+
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (x1 - x0) * (y1 - y0)
+			i := dst.PixOffset(dx, dy)
+			for c := range sum {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,284 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment builds an APP1 segment holding a little-endian TIFF structure
+// with a single orientation entry.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// rawGIF builds a GIF of n frames, each w by h. Only the block structure is
+// valid; the frames hold no pixel data.
+func rawGIF(n, w, h int) []byte {
+	data := []byte("GIF89a")
+	data = binary.LittleEndian.AppendUint16(data, uint16(w))
+	data = binary.LittleEndian.AppendUint16(data, uint16(h))
+	data = append(data, 0, 0, 0)
+	for range n {
+		data = append(data, 0x2C, 0, 0, 0, 0)
+		data = binary.LittleEndian.AppendUint16(data, uint16(w))
+		data = binary.LittleEndian.AppendUint16(data, uint16(h))
+		data = append(data, 0, 2, 0)
+	}
+	return append(data, 0x3B)
+}
+
+func jpegWithExif(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, nil)
+	if err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(orientation)...)
+	return append(out, data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := testImage(4, 2)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{
+			name: "No EXIF",
+			data: jpegWithExif(t, img, 1)[:2],
+			want: 0,
+		},
+		{
+			name: "Upright",
+			data: jpegWithExif(t, img, 1),
+			want: 1,
+		},
+		{
+			name: "Rotated 90 degrees",
+			data: jpegWithExif(t, img, 6),
+			want: 6,
+		},
+		{
+			name: "Out of range",
+			data: jpegWithExif(t, img, 9),
+			want: 0,
+		},
+		{
+			name: "Not a JPEG",
+			data: []byte("GIF89a"),
+			want: 0,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := jpegOrientation(tc.data); got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %d, got %d", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	data := jpegWithExif(t, testImage(40, 20), 6)
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Errorf("expected EXIF to be stripped")
+	}
+	if img.ContentType != "image/jpeg" {
+		t.Errorf("expected image/jpeg, got %s", img.ContentType)
+	}
+	// Orientation 6 turns a landscape image portrait.
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("expected 20x40, got %dx%d", img.Width, img.Height)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	var huge bytes.Buffer
+	err := png.Encode(&huge, image.NewGray(image.Rect(0, 0, MaxDimension+1, 1)))
+	if err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "Plain text",
+			data: []byte("hello, world"),
+			want: ErrUnsupportedType,
+		},
+		{
+			name: "HTML",
+			data: []byte("<html><script>alert(1)</script></html>"),
+			want: ErrUnsupportedType,
+		},
+		{
+			name: "Too wide",
+			data: huge.Bytes(),
+			want: ErrTooLarge,
+		},
+		{
+			name: "Too many frames",
+			data: rawGIF(MaxFrames+1, 1, 1),
+			want: ErrTooLarge,
+		},
+		{
+			name: "Too many pixels across frames",
+			data: rawGIF(5, 4000, 4000),
+			want: ErrTooLarge,
+		},
+		{
+			name: "Truncated GIF",
+			data: rawGIF(2, 1, 1)[:20],
+			want: errMalformedGIF,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Process(tc.data)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	g := &gif.GIF{}
+	for i := range 3 {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		frame.SetColorIndex(i, i, 1)
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	if err != nil {
+		t.Fatalf("gif.EncodeAll: %v", err)
+	}
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	out, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("gif.DecodeAll: %v", err)
+	}
+	if len(out.Image) != 3 {
+		t.Errorf("expected 3 frames, got %d", len(out.Image))
+	}
+	if img.Thumbnail.ContentType != "image/png" {
+		t.Errorf("expected image/png thumbnail, got %s", img.Thumbnail.ContentType)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantW, wantH  int
+	}{
+		{
+			name:  "Landscape",
+			width: 1000, height: 500,
+			wantW: ThumbnailSize, wantH: ThumbnailSize / 2,
+		},
+		{
+			name:  "Portrait",
+			width: 500, height: 1000,
+			wantW: ThumbnailSize / 2, wantH: ThumbnailSize,
+		},
+		{
+			name:  "Already small",
+			width: 100, height: 50,
+			wantW: 100, wantH: 50,
+		},
+		{
+			name:  "Very thin",
+			width: 2000, height: 1,
+			wantW: ThumbnailSize, wantH: 1,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			thumb := Thumbnail(testImage(tc.width, tc.height), ThumbnailSize)
+			w, h := thumb.Bounds().Dx(), thumb.Bounds().Dy()
+			if w != tc.wantW || h != tc.wantH {
+				t.Errorf("Test %v - '%s': FAIL: expected %dx%d, got %dx%d", i, tc.name, tc.wantW, tc.wantH, w, h)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := testImage(3, 2)
+	want := src.At(0, 0)
+
+	// Where the source's top-left pixel ends up for each orientation.
+	tests := []struct {
+		orientation int
+		x, y        int
+	}{
+		{orientation: 2, x: 2, y: 0},
+		{orientation: 3, x: 2, y: 1},
+		{orientation: 4, x: 0, y: 1},
+		{orientation: 5, x: 0, y: 0},
+		{orientation: 6, x: 1, y: 0},
+		{orientation: 7, x: 1, y: 2},
+		{orientation: 8, x: 0, y: 2},
+	}
+
+	for i, tc := range tests {
+		dst := orient(src, tc.orientation)
+		if got := dst.At(tc.x, tc.y); got != want {
+			t.Errorf("Test %v - 'orientation %d': FAIL: expected %v at (%d, %d), got %v", i, tc.orientation, want, tc.x, tc.y, got)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+// severity orders actions so the strictest match decides the outcome.
+var severity = map[Action]int{
+	ActionMask:   1,
+	ActionFlag:   2,
+	ActionReject: 3,
+}
+
+func (a Action) Valid() bool {
+	_, ok := severity[a]
+	return ok
+}
+
+type Kind string
+
+const (
+	KindWord  Kind = "word"
+	KindRegex Kind = "regex"
+)
+
+// Term is one entry in the moderation list. A word term may be a phrase of
+// several words; a regex term is matched against the raw text.
+type Term struct {
+	Kind    Kind
+	Pattern string
+	Action  Action
+}
+
+// Match is a span of the original text, in byte offsets, that a filter
+// objected to.
+type Match struct {
+	Term   string
+	Action Action
+	Start  int
+	End    int
+}
+
+// Filter finds objectionable spans in a piece of text. Tokens are the
+// output of Tokenize for the same text, shared so each filter doesn't
+// tokenize again.
+type Filter interface {
+	Match(text string, tokens []Token) []Match
+}
+
+const mask = "****"
+
+type Result struct {
+	Text    string
+	Action  Action
+	Matches []Match
+}
+
+func (r Result) Rejected() bool {
+	return r.Action == ActionReject
+}
+
+func (r Result) Flagged() bool {
+	return r.Action == ActionFlag
+}
+
+// Reasons lists the terms that matched, without duplicates.
+func (r Result) Reasons() []string {
+	seen := map[string]struct{}{}
+	reasons := []string{}
+	for _, match := range r.Matches {
+		if _, ok := seen[match.Term]; ok {
+			continue
+		}
+		seen[match.Term] = struct{}{}
+		reasons = append(reasons, match.Term)
+	}
+	return reasons
+}
+
+type Pipeline struct {
+	filters []Filter
+}
+
+func New(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Build compiles terms into a pipeline with one word list and one regex
+// filter per pattern.
+func Build(terms []Term) (*Pipeline, error) {
+	words := []Term{}
+	filters := []Filter{}
+	for _, term := range terms {
+		if !term.Action.Valid() {
+			return nil, fmt.Errorf("invalid action %q for term %q", term.Action, term.Pattern)
+		}
+		switch term.Kind {
+		case KindWord:
+			words = append(words, term)
+		case KindRegex:
+			rule, err := NewRegexRule(term.Pattern, term.Action)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, rule)
+		default:
+			return nil, fmt.Errorf("invalid kind %q for term %q", term.Kind, term.Pattern)
+		}
+	}
+	return New(append([]Filter{NewWordList(words)}, filters...)...), nil
+}
+
+// Moderate runs every filter over text and masks the spans whose action is
+// mask. The strictest action among all matches is returned in Action; an
+// empty Action means nothing matched.
+func (p *Pipeline) Moderate(text string) Result {
+	tokens := Tokenize(text)
+	result := Result{Text: text, Matches: []Match{}}
+	for _, filter := range p.filters {
+		result.Matches = append(result.Matches, filter.Match(text, tokens)...)
+	}
+
+	for _, match := range result.Matches {
+		if severity[match.Action] > severity[result.Action] {
+			result.Action = match.Action
+		}
+	}
+
+	masked := []Match{}
+	for _, match := range result.Matches {
+		if match.Action == ActionMask {
+			masked = append(masked, match)
+		}
+	}
+	result.Text = applyMasks(text, masked)
+
+	return result
+}
+
+func applyMasks(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		if match.Start < last {
+			// Overlaps a span that is already masked.
+			if match.End > last {
+				last = match.End
+			}
+			continue
+		}
+		b.WriteString(text[last:match.Start])
+		b.WriteString(mask)
+		last = match.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// WordList matches whole words and phrases after normalization, so
+// "KERFUFFLE!", "kërfuffle" and "k3rfuffle" all match "kerfuffle".
+type WordList struct {
+	phrases map[string][]phrase
+}
+
+type phrase struct {
+	term   string
+	words  Phrase
+	action Action
+}
+
+func NewWordList(terms []Term) *WordList {
+	wl := &WordList{phrases: map[string][]phrase{}}
+	for _, term := range terms {
+		words := NewPhrase(term.Pattern)
+		if len(words) == 0 {
+			continue
+		}
+		wl.phrases[words[0]] = append(wl.phrases[words[0]], phrase{
+			term:   term.Pattern,
+			words:  words,
+			action: term.Action,
+		})
+	}
+	return wl
+}
+
+func (wl *WordList) Match(text string, tokens []Token) []Match {
+	matches := []Match{}
+	for i, token := range tokens {
+		for _, p := range wl.phrases[token.Text] {
+			if !p.words.MatchesAt(tokens, i) {
+				continue
+			}
+			matches = append(matches, Match{
+				Term:   p.term,
+				Action: p.action,
+				Start:  token.Start,
+				End:    tokens[i+len(p.words)-1].End,
+			})
+		}
+	}
+	return matches
+}
+
+// Phrase is a word or phrase as the normalized words Tokenize would split
+// it into. It's empty if s holds no letters or digits.
+type Phrase []string
+
+func NewPhrase(s string) Phrase {
+	words := Phrase{}
+	for _, token := range Tokenize(s) {
+		words = append(words, token.Text)
+	}
+	return words
+}
+
+// MatchesAt reports whether the phrase's words appear as consecutive
+// tokens starting at tokens[i].
+func (p Phrase) MatchesAt(tokens []Token, i int) bool {
+	if len(p) == 0 || i+len(p) > len(tokens) {
+		return false
+	}
+	for j, word := range p {
+		if tokens[i+j].Text != word {
+			return false
+		}
+	}
+	return true
+}
+
+// In reports whether the phrase appears anywhere in tokens.
+func (p Phrase) In(tokens []Token) bool {
+	for i := range tokens {
+		if p.MatchesAt(tokens, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegexRule matches a regular expression against the raw text. Patterns
+// that should ignore case need the (?i) flag.
+type RegexRule struct {
+	pattern *regexp.Regexp
+	action  Action
+}
+
+func NewRegexRule(pattern string, action Action) (*RegexRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &RegexRule{pattern: re, action: action}, nil
+}
+
+func (rr *RegexRule) Match(text string, tokens []Token) []Match {
+	matches := []Match{}
+	for _, loc := range rr.pattern.FindAllStringIndex(text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		matches = append(matches, Match{
+			Term:   rr.pattern.String(),
+			Action: rr.action,
+			Start:  loc[0],
+			End:    loc[1],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,164 @@
+package moderation
+
+import (
+	"testing"
+)
+
+func TestModerate(t *testing.T) {
+	pipeline, err := Build([]Term{
+		{Kind: KindWord, Pattern: "kerfuffle", Action: ActionMask},
+		{Kind: KindWord, Pattern: "fornax", Action: ActionMask},
+		{Kind: KindWord, Pattern: "buy now", Action: ActionFlag},
+		{Kind: KindWord, Pattern: "sharbert", Action: ActionReject},
+		{Kind: KindRegex, Pattern: `(?i)\bcasino\d+\b`, Action: ActionMask},
+	})
+	if err != nil {
+		t.Fatalf("FAIL: error building pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		text       string
+		wantText   string
+		wantAction Action
+	}{
+		{
+			name:       "Clean text",
+			text:       "I had something interesting for breakfast",
+			wantText:   "I had something interesting for breakfast",
+			wantAction: "",
+		},
+		{
+			name:       "Trailing punctuation",
+			text:       "What a Kerfuffle!",
+			wantText:   "What a ****!",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Trailing newline",
+			text:       "look at fornax\nnow",
+			wantText:   "look at ****\nnow",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Accents and confusables",
+			text:       "kërfuffle and fоrn4x",
+			wantText:   "**** and ****",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Full-width characters",
+			text:       "ｆｏｒｎａｘ",
+			wantText:   "****",
+			wantAction: ActionMask,
+		},
+		{
+			name:       "Word inside another word is kept",
+			text:       "fornaxes are not a thing",
+			wantText:   "fornaxes are not a thing",
+			wantAction: "",
+		},
+		{
+			name:       "Phrase across punctuation",
+			text:       "Buy... NOW!",
+			wantText:   "Buy... NOW!",
+			wantAction: ActionFlag,
+		},
+		{
+			name:       "Strictest action wins",
+			text:       "kerfuffle sharbert",
+			wantText:   "**** sharbert",
+			wantAction: ActionReject,
+		},
+		{
+			name:       "Regex rule",
+			text:       "visit Casino24 today",
+			wantText:   "visit **** today",
+			wantAction: ActionMask,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := pipeline.Moderate(tc.text)
+			if result.Text != tc.wantText {
+				t.Errorf("Test %v - '%s': FAIL: expected text %q, got %q", i, tc.name, tc.wantText, result.Text)
+			}
+			if result.Action != tc.wantAction {
+				t.Errorf("Test %v - '%s': FAIL: expected action %q, got %q", i, tc.name, tc.wantAction, result.Action)
+			}
+		})
+	}
+}
+
+func TestBuildRejectsInvalidTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		term Term
+	}{
+		{
+			name: "Unknown action",
+			term: Term{Kind: KindWord, Pattern: "word", Action: "explode"},
+		},
+		{
+			name: "Unknown kind",
+			term: Term{Kind: "glob", Pattern: "word*", Action: ActionMask},
+		},
+		{
+			name: "Invalid regex",
+			term: Term{Kind: KindRegex, Pattern: "(unclosed", Action: ActionMask},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Build([]Term{tc.term})
+			if err == nil {
+				t.Errorf("Test %v - '%s': FAIL: expected error, got nil", i, tc.name)
+			}
+		})
+	}
+}
+
+func TestPhraseIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    bool
+	}{
+		{
+			name:    "Single word",
+			pattern: "kerfuffle",
+			text:    "What a K3rfuffle!",
+			want:    true,
+		},
+		{
+			name:    "Phrase across punctuation",
+			pattern: "buy now",
+			text:    "Buy... now",
+			want:    true,
+		},
+		{
+			name:    "Words out of order",
+			pattern: "buy now",
+			text:    "now buy",
+			want:    false,
+		},
+		{
+			name:    "Empty phrase never matches",
+			pattern: "!!!",
+			text:    "!!!",
+			want:    false,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewPhrase(tc.pattern).In(Tokenize(tc.text))
+			if got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
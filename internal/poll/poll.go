@@ -0,0 +1,81 @@
+// Package poll holds the rules for polls attached to chirps.
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/chirptext"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = 4
+	MaxOptionLength = 25
+
+	MinDuration = 5 * time.Minute
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+var (
+	ErrOptionCount     = fmt.Errorf("a poll must have between %d and %d options", MinOptions, MaxOptions)
+	ErrOptionEmpty     = errors.New("poll options can't be empty")
+	ErrOptionTooLong   = fmt.Errorf("poll options can be at most %d characters", MaxOptionLength)
+	ErrOptionDuplicate = errors.New("poll options must be different")
+	ErrClosesTooSoon   = fmt.Errorf("a poll must stay open for at least %v", MinDuration)
+	ErrClosesTooLate   = fmt.Errorf("a poll can stay open for at most %v", MaxDuration)
+)
+
+// Options checks and normalizes the options of a new poll, returning them
+// trimmed and NFC normalized in their original order. Options that differ
+// only in case count as duplicates.
+func Options(options []string) ([]string, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return nil, ErrOptionCount
+	}
+
+	normalized := make([]string, 0, len(options))
+	seen := map[string]bool{}
+	for _, option := range options {
+		option = strings.TrimSpace(norm.NFC.String(option))
+		if option == "" {
+			return nil, ErrOptionEmpty
+		}
+		if chirptext.Length(option) > MaxOptionLength {
+			return nil, ErrOptionTooLong
+		}
+		key := strings.ToLower(option)
+		if seen[key] {
+			return nil, ErrOptionDuplicate
+		}
+		seen[key] = true
+		normalized = append(normalized, option)
+	}
+	return normalized, nil
+}
+
+// ClosesAt checks the close time of a poll created at now.
+func ClosesAt(closesAt, now time.Time) error {
+	d := closesAt.Sub(now)
+	if d < MinDuration {
+		return ErrClosesTooSoon
+	}
+	if d > MaxDuration {
+		return ErrClosesTooLate
+	}
+	return nil
+}
+
+// Closed reports whether a poll closing at closesAt has closed by now.
+func Closed(closesAt, now time.Time) bool {
+	return !now.Before(closesAt)
+}
+
+// ShowResults reports whether a viewer may see a poll's counts: once
+// they've voted, or once it has closed, so results can't sway their vote.
+func ShowResults(voted bool, closesAt, now time.Time) bool {
+	return voted || Closed(closesAt, now)
+}
@@ -0,0 +1,160 @@
+package poll
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "Two options",
+			options: []string{"Yes", "No"},
+			want:    []string{"Yes", "No"},
+		},
+		{
+			name:    "Options are trimmed",
+			options: []string{"  Cats ", "Dogs\n", "Both"},
+			want:    []string{"Cats", "Dogs", "Both"},
+		},
+		{
+			name:    "Too few options",
+			options: []string{"Only one"},
+			wantErr: ErrOptionCount,
+		},
+		{
+			name:    "Too many options",
+			options: []string{"a", "b", "c", "d", "e"},
+			wantErr: ErrOptionCount,
+		},
+		{
+			name:    "Blank option",
+			options: []string{"Yes", "   "},
+			wantErr: ErrOptionEmpty,
+		},
+		{
+			name:    "Option too long",
+			options: []string{"Yes", "This option is far too long to fit"},
+			wantErr: ErrOptionTooLong,
+		},
+		{
+			name:    "Emoji count once",
+			options: []string{"👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽", "👎"},
+			want:    []string{"👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽👍🏽", "👎"},
+		},
+		{
+			name:    "Duplicates ignore case",
+			options: []string{"Yes", "YES"},
+			wantErr: ErrOptionDuplicate,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Options(tc.options)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Test %v - '%s': FAIL: expected error %v, got %v", i, tc.name, tc.wantErr, err)
+				return
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Test %v - '%s': FAIL: expected options %q, got %q", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClosesAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		closesAt time.Time
+		wantErr  error
+	}{
+		{
+			name:     "One day",
+			closesAt: now.Add(24 * time.Hour),
+		},
+		{
+			name:     "Shortest allowed",
+			closesAt: now.Add(MinDuration),
+		},
+		{
+			name:     "Longest allowed",
+			closesAt: now.Add(MaxDuration),
+		},
+		{
+			name:     "In the past",
+			closesAt: now.Add(-time.Hour),
+			wantErr:  ErrClosesTooSoon,
+		},
+		{
+			name:     "Too soon",
+			closesAt: now.Add(time.Minute),
+			wantErr:  ErrClosesTooSoon,
+		},
+		{
+			name:     "Too late",
+			closesAt: now.Add(MaxDuration + time.Second),
+			wantErr:  ErrClosesTooLate,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ClosesAt(tc.closesAt, now)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Test %v - '%s': FAIL: expected error %v, got %v", i, tc.name, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestShowResults(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		voted    bool
+		closesAt time.Time
+		want     bool
+	}{
+		{
+			name:     "Open and not voted",
+			closesAt: now.Add(time.Hour),
+			want:     false,
+		},
+		{
+			name:     "Open and voted",
+			voted:    true,
+			closesAt: now.Add(time.Hour),
+			want:     true,
+		},
+		{
+			name:     "Closed and not voted",
+			closesAt: now.Add(-time.Hour),
+			want:     true,
+		},
+		{
+			name:     "Closes right now",
+			closesAt: now,
+			want:     true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ShowResults(tc.voted, tc.closesAt, now)
+			if got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
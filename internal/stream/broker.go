@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event is one message on a stream. IDs are opaque to the broker; they only
+// need to be unique so a reconnecting client can say where it left off.
+// UserID is the user the event concerns, such as a chirp's author or a
+// notification's recipient, so subscribers can filter on it.
+// EmbeddedUserIDs are the authors of anything the event's chirp shares,
+// such as the original of a rechirp or a quote.
+type Event struct {
+	ID              string
+	Type            string
+	UserID          uuid.UUID
+	EmbeddedUserIDs []uuid.UUID
+	Data            []byte
+}
+
+// UserIDs returns UserID followed by EmbeddedUserIDs.
+func (e Event) UserIDs() []uuid.UUID {
+	return append([]uuid.UUID{e.UserID}, e.EmbeddedUserIDs...)
+}
+
+// subscriberBuffer is how many events a subscriber can fall behind before
+// it's dropped.
+const subscriberBuffer = 64
+
+// Broker fans events out to subscribers and keeps the most recent ones so
+// clients can catch up after reconnecting. Publishing never blocks: a
+// subscriber that can't keep up has its channel closed and must reconnect,
+// replaying what it missed from the buffer.
+type Broker struct {
+	mu          sync.Mutex
+	buffer      []Event
+	next        int
+	full        bool
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		buffer:      make([]Event, replaySize),
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	broker *Broker
+}
+
+// Close unsubscribes. It's safe to call after the broker has dropped the
+// subscription.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// remove must be called with the lock held.
+func (b *Broker) remove(s *Subscription) {
+	if _, ok := b.subscribers[s]; ok {
+		delete(b.subscribers, s)
+		close(s.c)
+	}
+}
+
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.buffer) > 0 {
+		b.buffer[b.next] = e
+		b.next = (b.next + 1) % len(b.buffer)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+
+	for s := range b.subscribers {
+		select {
+		case s.c <- e:
+		default:
+			b.remove(s)
+		}
+	}
+}
+
+// buffered returns the replay buffer oldest first. It must be called with
+// the lock held.
+func (b *Broker) buffered() []Event {
+	if !b.full {
+		return append([]Event(nil), b.buffer[:b.next]...)
+	}
+	events := append([]Event(nil), b.buffer[b.next:]...)
+	return append(events, b.buffer[:b.next]...)
+}
+
+// Subscribe registers a new subscriber and returns the buffered events
+// published after lastID, so nothing is missed between the replay and the
+// live events. If lastID is empty nothing is replayed; if it has already
+// left the buffer, everything buffered is.
+func (b *Broker) Subscribe(lastID string) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if lastID != "" {
+		replay = b.buffered()
+		for i, e := range replay {
+			if e.ID == lastID {
+				replay = replay[i+1:]
+				break
+			}
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: c, c: c, broker: b}
+	b.subscribers[s] = struct{}{}
+	return s, replay
+}
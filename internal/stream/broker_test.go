@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"strconv"
+	"testing"
+)
+
+func publishN(b *Broker, from, to int) {
+	for i := from; i <= to; i++ {
+		b.Publish(Event{ID: strconv.Itoa(i), Type: "test"})
+	}
+}
+
+func ids(events []Event) []string {
+	out := []string{}
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	b := NewBroker(4)
+	publishN(b, 1, 6)
+
+	tests := []struct {
+		name   string
+		lastID string
+		want   []string
+	}{
+		{
+			name:   "No last ID",
+			lastID: "",
+			want:   []string{},
+		},
+		{
+			name:   "Resume from the middle of the buffer",
+			lastID: "4",
+			want:   []string{"5", "6"},
+		},
+		{
+			name:   "Already up to date",
+			lastID: "6",
+			want:   []string{},
+		},
+		{
+			name:   "Last ID has left the buffer",
+			lastID: "1",
+			want:   []string{"3", "4", "5", "6"},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sub, replay := b.Subscribe(tc.lastID)
+			defer sub.Close()
+			if got := ids(replay); !equal(got, tc.want) {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSubscribeReceivesLiveEvents(t *testing.T) {
+	b := NewBroker(4)
+	sub, _ := b.Subscribe("")
+	defer sub.Close()
+
+	publishN(b, 1, 2)
+	for _, want := range []string{"1", "2"} {
+		if got := (<-sub.C).ID; got != want {
+			t.Errorf("expected event %s, got %s", want, got)
+		}
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(4)
+	slow, _ := b.Subscribe("")
+	fast, _ := b.Subscribe("")
+	defer fast.Close()
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish(Event{ID: strconv.Itoa(i)})
+		<-fast.C
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("expected %d events before the channel closed, got %d", subscriberBuffer, received)
+	}
+
+	// Closing after being dropped is a no-op.
+	slow.Close()
+}
@@ -0,0 +1,178 @@
+// Package webhook signs outbound webhook requests and schedules their
+// retries.
+//
+// Every request carries the delivery's ID, a Unix timestamp and a signature
+// of "<timestamp>.<body>" made with the subscription's secret:
+//
+//	Chirpy-Webhook-ID: 6f1c...
+//	Chirpy-Webhook-Timestamp: 1700000000
+//	Chirpy-Webhook-Signature: sha256=9a3e...
+//
+// Receivers should check the signature with Verify, reject stale
+// timestamps, and use the ID to ignore deliveries they've already handled.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	HeaderID        = "Chirpy-Webhook-ID"
+	HeaderTimestamp = "Chirpy-Webhook-Timestamp"
+	HeaderSignature = "Chirpy-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+
+	// BaseBackoff is the wait before the first retry; each one after
+	// doubles it, up to MaxBackoff.
+	BaseBackoff = 30 * time.Second
+	MaxBackoff  = 6 * time.Hour
+)
+
+// ErrDisallowedAddress is returned for a webhook URL that points at a
+// loopback, private or otherwise internal address.
+var ErrDisallowedAddress = errors.New("webhook address must be publicly routable")
+
+// reservedPrefixes are ranges AllowedAddr refuses on top of the private,
+// loopback, link-local and multicast ones netip knows about.
+var reservedPrefixes = []netip.Prefix{
+	// "This network", which Linux routes to the local host.
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Carrier-grade NAT, which some clouds use for internal services.
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// AllowedAddr reports whether webhooks may be sent to addr. Webhook URLs
+// are chosen by users, so any address that could reach the server's own
+// network is refused.
+func AllowedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL rejects a webhook URL whose host is a literal address that
+// AllowedAddr refuses. Hostnames are checked when they're resolved, by the
+// client NewClient returns.
+func CheckURL(u *url.URL) error {
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return ErrDisallowedAddress
+	}
+	addr, err := netip.ParseAddr(host)
+	if err == nil && !AllowedAddr(addr) {
+		return ErrDisallowedAddress
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client for sending webhooks. Every address it
+// connects to is checked with AllowedAddr after DNS resolution, so a
+// hostname can't be pointed somewhere internal, and redirects aren't
+// followed. Proxies are ignored, since they would connect on the client's
+// behalf without the check.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !AllowedAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrDisallowedAddress, address)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// NewSecret returns a random secret for signing a subscription's requests.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns the signature header value for a body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the one Sign makes for body.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// NewRequest builds a signed POST of body to url.
+func NewRequest(ctx context.Context, url, secret, deliveryID string, body []byte, now time.Time) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(HeaderID, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+	return req, nil
+}
+
+// Backoff returns how long to wait before retrying after the given number
+// of failed attempts.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	backoff := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return backoff
+}
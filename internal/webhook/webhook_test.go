@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "secret"
+	const timestamp = int64(1700000000)
+	body := []byte(`{"type":"chirp.created"}`)
+	signature := Sign(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{
+			name:      "Valid signature",
+			secret:    secret,
+			timestamp: timestamp,
+			body:      body,
+			signature: signature,
+			want:      true,
+		},
+		{
+			name:      "Wrong secret",
+			secret:    "other",
+			timestamp: timestamp,
+			body:      body,
+			signature: signature,
+		},
+		{
+			name:      "Replayed with a new timestamp",
+			secret:    secret,
+			timestamp: timestamp + 1,
+			body:      body,
+			signature: signature,
+		},
+		{
+			name:      "Tampered body",
+			secret:    secret,
+			timestamp: timestamp,
+			body:      []byte(`{"type":"chirp.deleted"}`),
+			signature: signature,
+		},
+		{
+			name:      "Missing prefix",
+			secret:    secret,
+			timestamp: timestamp,
+			body:      body,
+			signature: signature[len(signaturePrefix):],
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Verify(tc.secret, tc.timestamp, tc.body, tc.signature)
+			if got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{
+			name:     "No attempts yet",
+			attempts: 0,
+			want:     0,
+		},
+		{
+			name:     "First retry",
+			attempts: 1,
+			want:     30 * time.Second,
+		},
+		{
+			name:     "Doubles each time",
+			attempts: 4,
+			want:     4 * time.Minute,
+		},
+		{
+			name:     "Capped",
+			attempts: 30,
+			want:     MaxBackoff,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Backoff(tc.attempts)
+			if got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	body := []byte(`{"type":"user.upgraded"}`)
+	now := time.Unix(1700000000, 0)
+
+	req, err := NewRequest(context.Background(), "https://example.com/hook", "secret", "delivery-1", body, now)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	if got := req.Header.Get(HeaderID); got != "delivery-1" {
+		t.Errorf("expected ID %q, got %q", "delivery-1", got)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil || timestamp != now.Unix() {
+		t.Errorf("expected timestamp %d, got %q", now.Unix(), req.Header.Get(HeaderTimestamp))
+	}
+	sent, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !Verify("secret", timestamp, sent, req.Header.Get(HeaderSignature)) {
+		t.Errorf("signature doesn't verify")
+	}
+}
+
+func TestAllowedAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want bool
+	}{
+		{name: "Public IPv4", addr: "93.184.216.34", want: true},
+		{name: "Public IPv6", addr: "2606:2800:220:1::", want: true},
+		{name: "Loopback", addr: "127.0.0.1"},
+		{name: "IPv6 loopback", addr: "::1"},
+		{name: "IPv4-mapped loopback", addr: "::ffff:127.0.0.1"},
+		{name: "Private", addr: "10.1.2.3"},
+		{name: "Private 192.168", addr: "192.168.0.1"},
+		{name: "IPv6 unique local", addr: "fd00::1"},
+		{name: "Cloud metadata", addr: "169.254.169.254"},
+		{name: "IPv6 link-local", addr: "fe80::1"},
+		{name: "Unspecified", addr: "0.0.0.0"},
+		{name: "This network", addr: "0.1.2.3"},
+		{name: "Carrier-grade NAT", addr: "100.100.100.200"},
+		{name: "Multicast", addr: "224.0.0.1"},
+		{name: "Broadcast", addr: "255.255.255.255"},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := AllowedAddr(netip.MustParseAddr(tc.addr))
+			if got != tc.want {
+				t.Errorf("Test %v - '%s': FAIL: expected %v, got %v", i, tc.name, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "Hostname", url: "https://example.com/hook"},
+		{name: "Public address", url: "https://93.184.216.34/hook"},
+		{name: "Localhost", url: "http://LOCALHOST:8080/hook", wantErr: true},
+		{name: "Loopback address", url: "http://127.0.0.1/hook", wantErr: true},
+		{name: "IPv6 loopback address", url: "http://[::1]:8080/hook", wantErr: true},
+		{name: "Cloud metadata address", url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("url.Parse: %v", err)
+			}
+			err = CheckURL(u)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Test %v - '%s': FAIL: expected error %v, got %v", i, tc.name, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewClientRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// A hostname that resolves to loopback is refused when dialled.
+	target := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	_, err := NewClient().Post(target, "application/json", strings.NewReader("{}"))
+	if !errors.Is(err, ErrDisallowedAddress) {
+		t.Errorf("expected %v, got %v", ErrDisallowedAddress, err)
+	}
+}
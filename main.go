@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/blobstore"
 	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/events"
+	"github.com/chonginator/chirpy/internal/moderation"
+	"github.com/chonginator/chirpy/internal/stream"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,9 +22,22 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
 	jwtSecret      string
 	polkaKey       string
+	blobs          blobstore.BlobStore
+
+	deletionGracePeriod time.Duration
+	chirpEditWindow     time.Duration
+
+	moderationWords []moderation.Term
+	moderator       atomic.Pointer[moderation.Pipeline]
+
+	chirpStream        *stream.Broker
+	notificationStream *stream.Broker
+	events             *events.Bus
+	outboxWake         chan struct{}
 }
 
 func main() {
@@ -48,6 +68,34 @@ func main() {
 		log.Fatalf("POLKA_KEY environment variable is not set")
 	}
 
+	deletionGracePeriod, err := durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	if err != nil {
+		log.Fatalf("Error parsing ACCOUNT_DELETION_GRACE_PERIOD: %v", err)
+	}
+
+	chirpEditWindow, err := durationFromEnv("CHIRP_EDIT_WINDOW", 30*time.Minute)
+	if err != nil {
+		log.Fatalf("Error parsing CHIRP_EDIT_WINDOW: %v", err)
+	}
+
+	moderationWordList := os.Getenv("MODERATION_WORDS")
+	if moderationWordList == "" {
+		moderationWordList = defaultModerationWords
+	}
+	moderationWords, err := parseModerationWords(moderationWordList)
+	if err != nil {
+		log.Fatalf("Error parsing MODERATION_WORDS: %v", err)
+	}
+
+	mediaRoot := os.Getenv("MEDIA_ROOT")
+	if mediaRoot == "" {
+		mediaRoot = "media"
+	}
+	blobs, err := blobstore.NewLocalStore(mediaRoot)
+	if err != nil {
+		log.Fatalf("Error opening media store: %v", err)
+	}
+
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("Error connecting to database: %v", err)
@@ -58,14 +106,46 @@ func main() {
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
 		db:             dbQueries,
+		dbConn:         db,
 		platform:       platform,
 		jwtSecret:      jwtSecret,
 		polkaKey:       polkaKey,
+		blobs:          blobs,
+
+		deletionGracePeriod: deletionGracePeriod,
+		chirpEditWindow:     chirpEditWindow,
+
+		moderationWords: moderationWords,
+
+		chirpStream:        stream.NewBroker(chirpStreamReplaySize),
+		notificationStream: stream.NewBroker(0),
+		events:             events.NewBus(),
+		outboxWake:         make(chan struct{}, 1),
+	}
+
+	err = apiCfg.reloadModeration(context.Background())
+	if err != nil {
+		log.Fatalf("Error loading moderation terms: %v", err)
 	}
 
+	apiCfg.subscribeNotifications(apiCfg.events)
+	apiCfg.subscribeWebhooks(apiCfg.events)
+
+	go apiCfg.purgeDeletedUsers(context.Background(), time.Hour)
+	go apiCfg.purgeDeletedChirps(context.Background(), time.Hour)
+	go apiCfg.refreshModeration(context.Background(), time.Minute)
+	go apiCfg.publishScheduledChirps(context.Background(), time.Minute)
+	go apiCfg.purgeUnattachedMedia(context.Background(), time.Hour)
+	go apiCfg.deliverWebhooks(context.Background(), 10*time.Second)
+	go apiCfg.relayOutbox(context.Background(), outboxPollInterval)
+	go apiCfg.purgeProcessedOutbox(context.Background(), time.Hour)
+	go apiCfg.purgeExpiredMutedWords(context.Background(), time.Hour)
+	go apiCfg.listenEvents(context.Background(), dbURL)
+
 	mux := http.NewServeMux()
 	fileServer := http.FileServer(http.Dir(filepathRoot))
 	mux.Handle("/app/", http.StripPrefix("/app", apiCfg.middlewareMetricsInc(fileServer)))
+	mux.HandleFunc("GET /media/{key...}", apiCfg.handlerMediaServe)
 
 	mux.HandleFunc("GET /api/healthz", handlerReadiness)
 
@@ -75,22 +155,160 @@ func main() {
 
 	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
 	mux.HandleFunc("PUT /api/users", apiCfg.handlerUsersUpdate)
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.handlerUsersDelete)
+	mux.HandleFunc("GET /api/users/me/export", apiCfg.handlerUsersExport)
+	mux.HandleFunc("GET /api/users/{userID}/likes", apiCfg.handlerUsersLikesList)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerUsersFollow)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerUsersUnfollow)
+	mux.HandleFunc("PUT /api/users/me/privacy", apiCfg.handlerUsersPrivacy)
+	mux.HandleFunc("GET /api/users/me/follow-requests", apiCfg.handlerFollowRequestsList)
+	mux.HandleFunc("POST /api/users/me/follow-requests/{userID}/approve", apiCfg.handlerFollowRequestsApprove)
+	mux.HandleFunc("DELETE /api/users/me/follow-requests/{userID}", apiCfg.handlerFollowRequestsDecline)
+	mux.HandleFunc("GET /api/users/me/blocks", apiCfg.handlerBlocksList)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.handlerUsersBlock)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.handlerUsersUnblock)
+	mux.HandleFunc("GET /api/users/me/mutes", apiCfg.handlerMutesList)
+	mux.HandleFunc("GET /api/users/me/muted-words", apiCfg.handlerMutedWordsList)
+	mux.HandleFunc("POST /api/users/me/muted-words", apiCfg.handlerMutedWordsCreate)
+	mux.HandleFunc("PUT /api/users/me/muted-words/{mutedWordID}", apiCfg.handlerMutedWordsUpdate)
+	mux.HandleFunc("DELETE /api/users/me/muted-words/{mutedWordID}", apiCfg.handlerMutedWordsDelete)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.handlerUsersMute)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.handlerUsersUnmute)
+	mux.HandleFunc("PUT /api/users/me/message-settings", apiCfg.handlerUsersMessageSettings)
+	mux.HandleFunc("GET /api/users/me/bookmarks", apiCfg.handlerBookmarksList)
+	mux.HandleFunc("PUT /api/users/me/pins", apiCfg.handlerPinsReorder)
+	mux.HandleFunc("GET /api/users/{userID}/lists", apiCfg.handlerUsersListsList)
+
+	mux.HandleFunc("POST /api/lists", apiCfg.handlerListsCreate)
+	mux.HandleFunc("GET /api/lists/{listID}", apiCfg.handlerListsGet)
+	mux.HandleFunc("PUT /api/lists/{listID}", apiCfg.handlerListsUpdate)
+	mux.HandleFunc("DELETE /api/lists/{listID}", apiCfg.handlerListsDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/members", apiCfg.handlerListMembersList)
+	mux.HandleFunc("POST /api/lists/{listID}/members/{userID}", apiCfg.handlerListMembersAdd)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", apiCfg.handlerListMembersRemove)
+	mux.HandleFunc("GET /api/lists/{listID}/chirps", apiCfg.handlerListsChirps)
+
+	mux.HandleFunc("GET /api/conversations", apiCfg.handlerConversationsList)
+	mux.HandleFunc("POST /api/conversations", apiCfg.handlerConversationsCreate)
+	mux.HandleFunc("GET /api/conversations/unread", apiCfg.handlerConversationsUnread)
+	mux.HandleFunc("GET /api/conversations/{conversationID}", apiCfg.handlerConversationsGet)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerConversationsRead)
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerMessagesList)
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.handlerMessagesCreate)
+
+	mux.HandleFunc("GET /api/timeline", apiCfg.handlerTimeline)
 
 	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsRetrieve)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerChirpsGet)
+	mux.HandleFunc("GET /api/chirps/trash", apiCfg.handlerChirpsTrash)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.handlerChirpsRestore)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerChirpsUpdate)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerChirpsDelete)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.handlerChirpsHistory)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/replies", apiCfg.handlerChirpsReplies)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handlerChirpsLike)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handlerChirpsUnlike)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/likes", apiCfg.handlerChirpsLikesList)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.handlerChirpsRechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.handlerChirpsUndoRechirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.handlerChirpsReport)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/vote", apiCfg.handlerPollsVote)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.handlerChirpsBookmark)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.handlerChirpsUnbookmark)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.handlerChirpsPin)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.handlerChirpsUnpin)
+
+	mux.HandleFunc("POST /api/media", apiCfg.handlerMediaUpload)
+
+	mux.HandleFunc("POST /api/drafts", apiCfg.handlerDraftsCreate)
+	mux.HandleFunc("GET /api/drafts", apiCfg.handlerDraftsList)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.handlerDraftsUpdate)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.handlerDraftsDelete)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.handlerDraftsPublish)
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.handlerNotificationsList)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.handlerNotificationsRead)
+	mux.HandleFunc("POST /api/notifications/read-all", apiCfg.handlerNotificationsReadAll)
+	mux.HandleFunc("GET /api/notifications/preferences", apiCfg.handlerNotificationPreferencesGet)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCfg.handlerNotificationPreferencesUpdate)
+
+	mux.HandleFunc("GET /api/stream/chirps", apiCfg.handlerChirpsStream)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.handlerHashtagChirps)
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
 
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	mux.HandleFunc("POST /api/webhooks", apiCfg.handlerWebhooksCreate)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.handlerWebhooksList)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}", apiCfg.handlerWebhooksGet)
+	mux.HandleFunc("PUT /api/webhooks/{webhookID}", apiCfg.handlerWebhooksUpdate)
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.handlerWebhooksDelete)
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.handlerWebhookDeliveriesList)
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", apiCfg.handlerWebhookDeliveriesRedeliver)
+
+	// Everything under /admin needs a moderator or admin access token,
+	// except the dev-only reset.
+	moderator := func(h http.HandlerFunc) http.Handler {
+		return apiCfg.middlewareRequireRole(auth.RoleModerator, h)
+	}
+	admin := func(h http.HandlerFunc) http.Handler {
+		return apiCfg.middlewareRequireRole(auth.RoleAdmin, h)
+	}
+
+	mux.Handle("GET /admin/metrics", admin(apiCfg.handlerMetrics))
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
 
+	mux.Handle("GET /admin/users", moderator(apiCfg.handlerAdminUsersSearch))
+	mux.Handle("POST /admin/users/{userID}/suspension", moderator(apiCfg.handlerAdminUsersSuspend))
+	mux.Handle("DELETE /admin/users/{userID}/suspension", moderator(apiCfg.handlerAdminUsersUnsuspend))
+	mux.Handle("POST /admin/users/{userID}/ban", admin(apiCfg.handlerAdminUsersBan))
+	mux.Handle("DELETE /admin/users/{userID}/ban", admin(apiCfg.handlerAdminUsersUnban))
+	mux.Handle("POST /admin/users/{userID}/logout", admin(apiCfg.handlerAdminUsersLogout))
+	mux.Handle("PUT /admin/users/{userID}/chirpy-red", admin(apiCfg.handlerAdminUsersSetChirpyRed))
+	mux.Handle("PUT /admin/users/{userID}/role", admin(apiCfg.handlerAdminUsersSetRole))
+
+	mux.Handle("GET /admin/chirps/deleted", moderator(apiCfg.handlerAdminChirpsDeleted))
+	mux.Handle("GET /admin/chirps/{chirpID}", moderator(apiCfg.handlerAdminChirpsGet))
+	mux.Handle("DELETE /admin/chirps/{chirpID}", moderator(apiCfg.handlerAdminChirpsDelete))
+
+	mux.Handle("POST /admin/webhooks", admin(adminWebhooks(apiCfg.handlerWebhooksCreate)))
+	mux.Handle("GET /admin/webhooks", admin(adminWebhooks(apiCfg.handlerWebhooksList)))
+	mux.Handle("GET /admin/webhooks/{webhookID}", admin(adminWebhooks(apiCfg.handlerWebhooksGet)))
+	mux.Handle("PUT /admin/webhooks/{webhookID}", admin(adminWebhooks(apiCfg.handlerWebhooksUpdate)))
+	mux.Handle("DELETE /admin/webhooks/{webhookID}", admin(adminWebhooks(apiCfg.handlerWebhooksDelete)))
+	mux.Handle("GET /admin/webhooks/{webhookID}/deliveries", admin(adminWebhooks(apiCfg.handlerWebhookDeliveriesList)))
+	mux.Handle("POST /admin/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", admin(adminWebhooks(apiCfg.handlerWebhookDeliveriesRedeliver)))
+
+	mux.Handle("GET /admin/audit", admin(apiCfg.handlerAdminAuditList))
+	mux.Handle("GET /admin/audit/verify", admin(apiCfg.handlerAdminAuditVerify))
+
+	mux.Handle("GET /admin/moderation/terms", moderator(apiCfg.handlerModerationTermsList))
+	mux.Handle("POST /admin/moderation/terms", moderator(apiCfg.handlerModerationTermsCreate))
+	mux.Handle("DELETE /admin/moderation/terms/{termID}", moderator(apiCfg.handlerModerationTermsDelete))
+	mux.Handle("GET /admin/moderation/flags", moderator(apiCfg.handlerChirpFlagsList))
+	mux.Handle("GET /admin/moderation/cases", moderator(apiCfg.handlerModerationCasesList))
+	mux.Handle("GET /admin/moderation/cases/{caseID}", moderator(apiCfg.handlerModerationCasesGet))
+	mux.Handle("POST /admin/moderation/cases/{caseID}/claim", moderator(apiCfg.handlerModerationCasesClaim))
+	mux.Handle("DELETE /admin/moderation/cases/{caseID}/claim", moderator(apiCfg.handlerModerationCasesRelease))
+	mux.Handle("POST /admin/moderation/cases/{caseID}/resolve", moderator(apiCfg.handlerModerationCasesResolve))
+
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: middlewareRequestID(mux),
 	}
 
 	log.Printf("Serving on port: %s\n", port)
 	log.Fatal(srv.ListenAndServe())
 }
+
+// durationFromEnv reads an optional duration such as "720h" from the
+// environment, falling back to the default when the variable is unset.
+func durationFromEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
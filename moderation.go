@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const defaultModerationWords = "kerfuffle,sharbert,fornax"
+
+// parseModerationWords reads a comma-separated word list from config. Each
+// entry is a word or phrase, optionally followed by ":flag" or ":reject";
+// entries without an action are masked.
+func parseModerationWords(list string) ([]moderation.Term, error) {
+	terms := []moderation.Term{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		term := moderation.Term{
+			Kind:    moderation.KindWord,
+			Pattern: entry,
+			Action:  moderation.ActionMask,
+		}
+		if pattern, action, ok := strings.Cut(entry, ":"); ok {
+			term.Pattern = strings.TrimSpace(pattern)
+			term.Action = moderation.Action(strings.TrimSpace(action))
+			if !term.Action.Valid() {
+				return nil, fmt.Errorf("invalid action %q for %q", action, pattern)
+			}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// reloadModeration rebuilds the moderation pipeline from the configured
+// word list plus the terms admins have added to the database.
+func (cfg *apiConfig) reloadModeration(ctx context.Context) error {
+	dbTerms, err := cfg.db.GetModerationTerms(ctx)
+	if err != nil {
+		return err
+	}
+
+	terms := append([]moderation.Term{}, cfg.moderationWords...)
+	for _, term := range dbTerms {
+		terms = append(terms, moderation.Term{
+			Kind:    moderation.Kind(term.Kind),
+			Pattern: term.Pattern,
+			Action:  moderation.Action(term.Action),
+		})
+	}
+
+	pipeline, err := moderation.Build(terms)
+	if err != nil {
+		return err
+	}
+	cfg.moderator.Store(pipeline)
+	return nil
+}
+
+// flagChirp records why the moderation pipeline flagged a chirp and puts
+// the chirp in the moderation queue.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, reasons []string) error {
+	modCase, err := q.OpenModerationCase(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+		ChirpID: chirpID,
+		Reasons: reasons,
+		CaseID:  uuid.NullUUID{UUID: modCase.ID, Valid: true},
+	})
+	return err
+}
+
+// refreshModeration periodically reloads the pipeline so that changes made
+// through another replica's admin API are picked up here too.
+func (cfg *apiConfig) refreshModeration(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := cfg.reloadModeration(ctx)
+		if err != nil {
+			log.Printf("Error reloading moderation terms: %v", err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chonginator/chirpy/internal/auth"
+	"github.com/chonginator/chirpy/internal/database"
+)
+
+var (
+	errAccountBanned    = errors.New("account is banned")
+	errAccountSuspended = errors.New("account is suspended")
+)
+
+type accessTokenKey struct{}
+
+// makeAccessToken issues an access JWT carrying the user's current roles.
+// The roles claim is only informational, for clients deciding what to
+// show: middlewareRequireRole reads the role from the database on every
+// request instead of trusting it.
+func (cfg *apiConfig) makeAccessToken(user database.User) (string, error) {
+	return auth.MakeJWT(user.ID, auth.Role(user.Role).Roles(), cfg.jwtSecret, time.Hour)
+}
+
+// accountRestriction returns why a user can't log in, refresh their
+// session or post, or nil if they can.
+func accountRestriction(user database.User) error {
+	if user.BannedAt.Valid {
+		return errAccountBanned
+	}
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(time.Now().UTC()) {
+		return fmt.Errorf("%w until %s", errAccountSuspended, user.SuspendedUntil.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// isAccountRestriction reports whether err came from accountRestriction.
+func isAccountRestriction(err error) bool {
+	return errors.Is(err, errAccountBanned) || errors.Is(err, errAccountSuspended)
+}
+
+// middlewareRequireRole only lets through requests from users who hold
+// role. The user's role and any ban or suspension are read from the
+// database rather than trusted from the access JWT, so demoting or
+// suspending someone takes effect straight away. Handlers behind it can
+// get the token, with the user's current roles, with requestAccessToken.
+func (cfg *apiConfig) middlewareRequireRole(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+			return
+		}
+
+		token, err := auth.ParseAccessToken(accessToken, cfg.jwtSecret)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+			return
+		}
+
+		user, err := cfg.db.GetUserByID(r.Context(), token.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find user", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get user", err)
+			return
+		}
+		err = accountRestriction(user)
+		if err != nil {
+			respondWithError(w, http.StatusForbidden, err.Error(), err)
+			return
+		}
+
+		token.Roles = auth.Role(user.Role).Roles()
+		if !token.HasRole(role) {
+			err := fmt.Errorf("%s role required", role)
+			respondWithError(w, http.StatusForbidden, err.Error(), err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), accessTokenKey{}, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requestAccessToken(r *http.Request) auth.AccessToken {
+	token, _ := r.Context().Value(accessTokenKey{}).(auth.AccessToken)
+	return token
+}
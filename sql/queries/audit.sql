@@ -0,0 +1,57 @@
+-- name: LockAuditChain :exec
+-- Serializes writers until the transaction ends, so each new entry chains
+-- onto the latest one. Nothing else touching audit_events waits on it.
+SELECT pg_advisory_xact_lock(hashtext('audit_events'));
+
+-- name: GetLatestAuditEvent :one
+SELECT * FROM audit_events
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+  id,
+  created_at,
+  actor_id,
+  action,
+  target_type,
+  target_id,
+  ip,
+  user_agent,
+  request_id,
+  metadata,
+  prev_hash,
+  hash
+) VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8,
+  $9,
+  $10,
+  $11,
+  $12
+);
+
+-- name: GetAuditEvents :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg(actor_id)::UUID IS NULL OR actor_id = sqlc.narg(actor_id)::UUID)
+AND (sqlc.narg(action)::TEXT IS NULL OR action = sqlc.narg(action)::TEXT)
+AND (sqlc.narg(target_type)::TEXT IS NULL OR target_type = sqlc.narg(target_type)::TEXT)
+AND (sqlc.narg(target_id)::TEXT IS NULL OR target_id = sqlc.narg(target_id)::TEXT)
+AND (sqlc.narg(request_id)::TEXT IS NULL OR request_id = sqlc.narg(request_id)::TEXT)
+AND (sqlc.narg(since)::TIMESTAMP IS NULL OR created_at >= sqlc.narg(since)::TIMESTAMP)
+AND (sqlc.narg(until)::TIMESTAMP IS NULL OR created_at < sqlc.narg(until)::TIMESTAMP)
+AND (sqlc.narg(before)::BIGINT IS NULL OR id < sqlc.narg(before)::BIGINT)
+ORDER BY id DESC
+LIMIT @max_results;
+
+-- name: GetAuditEventsAfter :many
+SELECT * FROM audit_events
+WHERE id > $1
+ORDER BY id ASC
+LIMIT $2;
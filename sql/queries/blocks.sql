@@ -0,0 +1,62 @@
+-- name: CreateBlock :one
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetBlock :one
+SELECT * FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+-- name: DeleteBlock :exec
+DELETE FROM blocks
+WHERE blocker_id = $1
+AND blocked_id = $2;
+
+-- name: GetBlockedUsers :many
+SELECT blocks.blocked_id AS user_id, users.username, blocks.created_at
+FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = $1
+ORDER BY blocks.created_at DESC;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocker_id = @user_id AND blocked_id = @other_id)
+  OR (blocker_id = @other_id AND blocked_id = @user_id)
+);
+
+-- name: CreateMute :one
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetMute :one
+SELECT * FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+-- name: DeleteMute :exec
+DELETE FROM mutes
+WHERE muter_id = $1
+AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT mutes.muted_id AS user_id, users.username, mutes.created_at
+FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = $1
+ORDER BY mutes.created_at DESC;
+
+-- name: GetUserRelations :many
+SELECT b.blocked_id AS user_id, 'blocking'::TEXT AS kind
+FROM blocks AS b WHERE b.blocker_id = @viewer_id
+UNION ALL
+SELECT bb.blocker_id AS user_id, 'blocked_by'::TEXT AS kind
+FROM blocks AS bb WHERE bb.blocked_id = @viewer_id
+UNION ALL
+SELECT m.muted_id AS user_id, 'muting'::TEXT AS kind
+FROM mutes AS m WHERE m.muter_id = @viewer_id;
@@ -0,0 +1,34 @@
+-- name: CreateBookmark :one
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetBookmark :one
+SELECT * FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetBookmarkedChirps :many
+-- Pages are keyed by the chirp ID of the last bookmark on the previous
+-- page.
+SELECT chirps.* FROM chirps
+JOIN bookmarks ON bookmarks.chirp_id = chirps.id
+WHERE bookmarks.user_id = @user_id
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+AND (
+  sqlc.narg(before)::UUID IS NULL
+  OR (bookmarks.created_at, bookmarks.chirp_id) < (
+    SELECT cursor.created_at, cursor.chirp_id FROM bookmarks AS cursor
+    WHERE cursor.user_id = @user_id
+    AND cursor.chirp_id = sqlc.narg(before)::UUID
+  )
+)
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT @max_results;
@@ -1,22 +1,107 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quoted_chirp_id, reply_to_id, visibility)
 VALUES (
   gen_random_uuid(),
   NOW(),
   NOW(),
   $1,
-  $2
+  $2,
+  $3,
+  $4,
+  $5
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND hidden_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpByID :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = $1
+AND deleted_at IS NULL;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = $1
+AND deleted_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: GetChirpsByHashtag :many
+SELECT * FROM chirps
+WHERE id IN (
+  SELECT chirp_id FROM chirp_hashtags
+  WHERE tag = $1
+)
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(@ids::UUID[])
+AND deleted_at IS NULL
+AND hidden_at IS NULL;
+
+-- name: GetChirpByIDForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1
+AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1;
+
+-- name: GetDeletedChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = @user_id
+AND deleted_at > @deleted_after::TIMESTAMP
+ORDER BY deleted_at DESC;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = @id
+AND user_id = @user_id
+AND deleted_at > @deleted_after::TIMESTAMP
+RETURNING *;
+
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE deleted_at <= @deleted_before::TIMESTAMP;
+
+-- name: GetChirpByIDWithDeleted :one
+SELECT * FROM chirps
+WHERE id = $1;
+
+-- name: GetDeletedChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: GetChirpReplies :many
+SELECT * FROM chirps
+WHERE reply_to_id = $1
+AND deleted_at IS NULL
+AND hidden_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: HideChirp :one
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+RETURNING *;
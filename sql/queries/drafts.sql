@@ -0,0 +1,48 @@
+-- name: CreateDraft :one
+INSERT INTO chirp_drafts (id, created_at, updated_at, user_id, body, quoted_chirp_id, publish_at, visibility)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING *;
+
+-- name: GetDraftsByUserID :many
+SELECT * FROM chirp_drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC;
+
+-- name: GetDraftForUpdate :one
+SELECT * FROM chirp_drafts
+WHERE id = $1
+AND user_id = $2
+FOR UPDATE;
+
+-- name: UpdateDraft :one
+UPDATE chirp_drafts
+SET body = $3, quoted_chirp_id = $4, publish_at = $5, visibility = $6, publish_error = NULL, updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+DELETE FROM chirp_drafts
+WHERE id = $1
+AND user_id = $2;
+
+-- name: ClaimDueDraft :one
+SELECT * FROM chirp_drafts
+WHERE publish_at <= NOW()
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: SetDraftPublishError :exec
+UPDATE chirp_drafts
+SET publish_at = NULL, publish_error = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,19 @@
+-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, start_index, end_index)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, username, user_id, start_index, end_index)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetChirpMentionsByChirpIDs :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
@@ -0,0 +1,13 @@
+-- name: NotifyChirpEvent :exec
+SELECT pg_notify(
+  'chirp_events',
+  json_build_object(
+    'id', nextval('chirp_event_seq'),
+    'type', @type::TEXT,
+    'chirp_id', @chirp_id::UUID,
+    'user_id', @user_id::UUID
+  )::TEXT
+);
+
+-- name: NotifyUser :exec
+SELECT pg_notify('user_notifications', @payload::TEXT);
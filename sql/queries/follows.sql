@@ -0,0 +1,66 @@
+-- name: CreateFollow :one
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetFollow :one
+SELECT * FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
+
+-- name: GetTimelineChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND hidden_at IS NULL
+AND (
+  user_id = @user_id
+  OR user_id IN (
+    SELECT followee_id FROM follows
+    WHERE follower_id = @user_id
+  )
+)
+ORDER BY created_at DESC;
+
+-- name: CreateFollowRequest :one
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetFollowRequest :one
+SELECT * FROM follow_requests
+WHERE requester_id = $1
+AND target_id = $2;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1
+AND target_id = $2;
+
+-- name: GetFollowRequests :many
+SELECT follow_requests.requester_id AS user_id, users.username, follow_requests.created_at
+FROM follow_requests
+JOIN users ON users.id = follow_requests.requester_id
+WHERE follow_requests.target_id = $1
+ORDER BY follow_requests.created_at ASC;
+
+-- name: ApproveAllFollowRequests :many
+WITH approved AS (
+  DELETE FROM follow_requests
+  WHERE target_id = $1
+  RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW() FROM approved
+ON CONFLICT DO NOTHING
+RETURNING *;
@@ -0,0 +1,53 @@
+-- name: LikeChirp :exec
+WITH inserted AS (
+  INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+  VALUES ($1, $2, NOW())
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count + 1
+WHERE id IN (SELECT chirp_id FROM inserted);
+
+-- name: UnlikeChirp :exec
+WITH deleted AS (
+  DELETE FROM chirp_likes
+  WHERE chirp_likes.user_id = $1 AND chirp_likes.chirp_id = $2
+  RETURNING chirp_id
+)
+UPDATE chirps
+SET like_count = like_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
+
+-- name: GetChirpLikes :many
+SELECT chirp_likes.user_id, chirp_likes.created_at, users.username
+FROM chirp_likes
+JOIN users ON users.id = chirp_likes.user_id
+WHERE chirp_likes.chirp_id = $1
+ORDER BY chirp_likes.created_at DESC;
+
+-- name: GetChirpsLikedByUser :many
+SELECT chirps.* FROM chirps
+JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirp_likes.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY chirp_likes.created_at DESC;
+
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = @user_id
+AND chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: RemoveLikesOfUsersPastGracePeriod :exec
+UPDATE chirps
+SET like_count = chirps.like_count - purged.likes
+FROM (
+  SELECT chirp_likes.chirp_id, COUNT(*) AS likes
+  FROM chirp_likes
+  JOIN users ON users.id = chirp_likes.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  GROUP BY chirp_likes.chirp_id
+) AS purged
+WHERE chirps.id = purged.chirp_id;
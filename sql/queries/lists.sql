@@ -0,0 +1,65 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, private)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetList :one
+SELECT * FROM lists
+WHERE id = $1;
+
+-- name: GetListsByOwner :many
+SELECT * FROM lists
+WHERE owner_id = @owner_id
+AND (@include_private::BOOLEAN OR NOT private)
+ORDER BY created_at ASC;
+
+-- name: CountListsByOwner :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, private = $5, updated_at = NOW()
+WHERE id = $1
+AND owner_id = $2
+RETURNING *;
+
+-- name: DeleteList :exec
+DELETE FROM lists
+WHERE id = $1
+AND owner_id = $2;
+
+-- name: AddListMember :one
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetListMember :one
+SELECT * FROM list_members
+WHERE list_id = $1
+AND user_id = $2;
+
+-- name: DeleteListMember :exec
+DELETE FROM list_members
+WHERE list_id = $1
+AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: GetListMembers :many
+SELECT list_members.user_id, users.username, list_members.created_at
+FROM list_members
+JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1
+ORDER BY list_members.created_at ASC;
+
+-- name: GetListChirps :many
+SELECT chirps.* FROM chirps
+JOIN list_members ON list_members.user_id = chirps.user_id
+WHERE list_members.list_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY chirps.created_at DESC;
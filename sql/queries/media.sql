@@ -0,0 +1,38 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, byte_size, width, height, storage_key, thumbnail_key)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  $7,
+  $8
+)
+RETURNING *;
+
+-- name: AttachMedia :one
+UPDATE media
+SET chirp_id = @chirp_id::UUID, position = @position::INTEGER, alt_text = @alt_text
+WHERE id = @id
+AND user_id = @user_id::UUID
+AND chirp_id IS NULL
+RETURNING *;
+
+-- name: GetMediaByChirpIDs :many
+SELECT * FROM media
+WHERE chirp_id = ANY(@chirp_ids::UUID[])
+ORDER BY chirp_id, position;
+
+-- name: PurgeUnattachedMedia :many
+DELETE FROM media
+WHERE chirp_id IS NULL
+AND created_at < $1
+RETURNING storage_key, thumbnail_key;
+
+-- name: GetMediaByKey :one
+SELECT * FROM media
+WHERE storage_key = @key
+OR thumbnail_key = @key;
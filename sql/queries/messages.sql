@@ -0,0 +1,112 @@
+-- name: CreateConversation :one
+-- Returns no rows if a one-to-one conversation with the key already
+-- exists.
+INSERT INTO conversations (id, created_at, updated_at, created_by, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING *;
+
+-- name: GetConversationByDirectKey :one
+SELECT * FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at, last_read_at)
+VALUES ($1, $2, NOW(), NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationForMember :one
+SELECT conversations.* FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = @id
+AND conversation_members.user_id = @user_id;
+
+-- name: GetConversations :many
+-- Messages from users blocked either way aren't counted as unread, since
+-- they're left out of the conversation's messages.
+SELECT sqlc.embed(conversations), (
+  SELECT COUNT(*) FROM messages
+  WHERE messages.conversation_id = conversations.id
+  AND messages.sender_id <> @user_id
+  AND messages.created_at > conversation_members.last_read_at
+  AND NOT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocks.blocker_id = @user_id AND blocks.blocked_id = messages.sender_id)
+    OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = @user_id)
+  )
+) AS unread_count
+FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = @user_id
+ORDER BY conversations.updated_at DESC;
+
+-- name: GetConversationMembers :many
+SELECT conversation_members.conversation_id, conversation_members.user_id, users.username,
+  conversation_members.joined_at, conversation_members.last_read_at
+FROM conversation_members
+JOIN users ON users.id = conversation_members.user_id
+WHERE conversation_members.conversation_id = ANY(@conversation_ids::UUID[])
+ORDER BY conversation_members.joined_at ASC, conversation_members.user_id ASC;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages
+WHERE id = $1
+AND conversation_id = $2;
+
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE messages.conversation_id = @conversation_id
+AND (
+  sqlc.narg(before)::UUID IS NULL
+  OR (messages.created_at, messages.id) < (
+    SELECT cursor.created_at, cursor.id FROM messages AS cursor
+    WHERE cursor.id = sqlc.narg(before)::UUID
+  )
+)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT @max_results;
+
+-- name: GetLatestMessages :many
+-- The newest message in each conversation that user_id can see, leaving
+-- out senders blocked either way.
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(@conversation_ids::UUID[])
+AND NOT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocks.blocker_id = @user_id AND blocks.blocked_id = messages.sender_id)
+  OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = @user_id)
+)
+ORDER BY conversation_id, created_at DESC, id DESC;
+
+-- name: CountUnreadMessages :one
+-- Counts across all of the user's conversations unless one is given.
+-- Messages from users blocked either way aren't counted, as in
+-- GetConversations.
+SELECT COUNT(*) FROM messages
+JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+WHERE conversation_members.user_id = @user_id
+AND (sqlc.narg(conversation_id)::UUID IS NULL OR messages.conversation_id = sqlc.narg(conversation_id)::UUID)
+AND messages.sender_id <> @user_id
+AND messages.created_at > conversation_members.last_read_at
+AND NOT EXISTS (
+  SELECT 1 FROM blocks
+  WHERE (blocks.blocker_id = @user_id AND blocks.blocked_id = messages.sender_id)
+  OR (blocks.blocker_id = messages.sender_id AND blocks.blocked_id = @user_id)
+);
+
+-- name: MarkConversationRead :exec
+-- Read receipts only move forward.
+UPDATE conversation_members
+SET last_read_at = GREATEST(last_read_at, @read_at::TIMESTAMP)
+WHERE conversation_id = @conversation_id
+AND user_id = @user_id;
@@ -0,0 +1,134 @@
+-- name: CreateModerationTerm :one
+INSERT INTO moderation_terms (id, created_at, updated_at, kind, pattern, action)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3
+)
+RETURNING *;
+
+-- name: GetModerationTerms :many
+SELECT * FROM moderation_terms
+ORDER BY created_at ASC;
+
+-- name: DeleteModerationTerm :execrows
+DELETE FROM moderation_terms
+WHERE id = $1;
+
+-- name: CreateChirpFlag :one
+INSERT INTO chirp_flags (id, created_at, chirp_id, reasons, case_id)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3
+)
+RETURNING *;
+
+-- name: GetChirpFlags :many
+SELECT * FROM chirp_flags
+ORDER BY created_at ASC;
+
+-- name: OpenModerationCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, status)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  'open'
+)
+ON CONFLICT (chirp_id) WHERE status <> 'resolved'
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: GetModerationCase :one
+SELECT * FROM moderation_cases
+WHERE id = $1;
+
+-- name: GetModerationCaseForUpdate :one
+SELECT * FROM moderation_cases
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetModerationCases :many
+SELECT sqlc.embed(moderation_cases),
+  (SELECT COUNT(*) FROM reports WHERE reports.case_id = moderation_cases.id) AS report_count,
+  EXISTS (SELECT 1 FROM chirp_flags WHERE chirp_flags.case_id = moderation_cases.id) AS flagged
+FROM moderation_cases
+WHERE (
+  sqlc.narg('status')::TEXT IS NULL AND status <> 'resolved'
+  OR status = sqlc.narg('status')::TEXT
+)
+AND (sqlc.narg('claimed_by')::UUID IS NULL OR claimed_by = sqlc.narg('claimed_by')::UUID)
+ORDER BY created_at ASC
+LIMIT @max_results;
+
+-- name: ClaimModerationCase :one
+UPDATE moderation_cases
+SET status = 'claimed', claimed_by = @claimed_by, claimed_at = NOW(), updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: ReleaseModerationCase :one
+UPDATE moderation_cases
+SET status = 'open', claimed_by = NULL, claimed_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ResolveModerationCase :one
+UPDATE moderation_cases
+SET status = 'resolved', resolution = @resolution, resolved_by = @resolved_by,
+  resolved_at = NOW(), updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: CreateModerationDecision :one
+INSERT INTO moderation_decisions (id, created_at, case_id, moderator_id, action, note)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetModerationDecisions :many
+SELECT * FROM moderation_decisions
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, case_id, chirp_id, reporter_id, reason, details)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING
+RETURNING *;
+
+-- name: GetReportByReporter :one
+SELECT * FROM reports
+WHERE chirp_id = $1
+AND reporter_id = $2;
+
+-- name: GetCaseReports :many
+SELECT * FROM reports
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetCaseChirpFlags :many
+SELECT * FROM chirp_flags
+WHERE case_id = $1
+ORDER BY created_at ASC;
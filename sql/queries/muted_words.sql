@@ -0,0 +1,42 @@
+-- name: UpsertMutedWord :one
+INSERT INTO muted_words (id, created_at, updated_at, user_id, kind, pattern, action, expires_at)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+)
+ON CONFLICT (user_id, kind, pattern) DO UPDATE
+SET action = EXCLUDED.action, expires_at = EXCLUDED.expires_at, updated_at = NOW()
+RETURNING *;
+
+-- name: GetActiveMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1
+AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC;
+
+-- name: CountActiveMutedWords :one
+SELECT COUNT(*) FROM muted_words
+WHERE user_id = $1
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: UpdateMutedWord :one
+UPDATE muted_words
+SET action = @action, expires_at = @expires_at, updated_at = NOW()
+WHERE id = @id
+AND user_id = @user_id
+RETURNING *;
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1
+AND user_id = $2;
+
+-- name: DeleteExpiredMutedWords :execrows
+DELETE FROM muted_words
+WHERE expires_at <= NOW();
@@ -0,0 +1,54 @@
+-- name: CreateNotification :one
+-- Returns no rows if the user has turned the type off or was already
+-- notified about the event.
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id, event_key)
+SELECT gen_random_uuid(), NOW(), @user_id::UUID, @type::TEXT, sqlc.narg(actor_id)::UUID, sqlc.narg(chirp_id)::UUID, @event_key::TEXT
+WHERE NOT EXISTS (
+  SELECT 1 FROM notification_preferences
+  WHERE notification_preferences.user_id = @user_id::UUID
+  AND notification_preferences.type = @type::TEXT
+  AND NOT notification_preferences.enabled
+)
+ON CONFLICT (user_id, event_key) DO NOTHING
+RETURNING *;
+
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE notifications.user_id = @user_id
+AND (
+  sqlc.narg(before)::UUID IS NULL
+  OR (notifications.created_at, notifications.id) < (
+    SELECT cursor.created_at, cursor.id FROM notifications AS cursor
+    WHERE cursor.id = sqlc.narg(before)::UUID
+  )
+)
+AND (NOT @unread_only::BOOLEAN OR notifications.read_at IS NULL)
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT @max_results;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1
+AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1
+AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE
+SET enabled = EXCLUDED.enabled;
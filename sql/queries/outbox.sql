@@ -0,0 +1,37 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (id, created_at, event_type, payload, next_attempt_at)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  NOW()
+);
+
+-- name: NotifyOutbox :exec
+-- Wakes the relay. Postgres holds the notification until the transaction
+-- commits, and drops it if it rolls back.
+SELECT pg_notify('outbox', '');
+
+-- name: ClaimOutboxEvents :many
+SELECT * FROM outbox
+WHERE processed_at IS NULL
+AND next_attempt_at <= NOW()
+AND attempts < @max_attempts::INTEGER
+ORDER BY created_at ASC
+LIMIT @max_results
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventProcessed :exec
+UPDATE outbox
+SET processed_at = NOW(), attempts = attempts + 1, last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+WHERE id = $1;
+
+-- name: PurgeProcessedOutboxEvents :execrows
+DELETE FROM outbox
+WHERE processed_at < $1;
@@ -0,0 +1,40 @@
+-- name: PinChirp :one
+-- New pins go after the existing ones. Returns no rows if the chirp is
+-- already pinned.
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+SELECT @user_id::UUID, @chirp_id::UUID, COALESCE(MAX(position) + 1, 0), NOW()
+FROM pinned_chirps
+WHERE user_id = @user_id::UUID
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetPin :one
+SELECT * FROM pinned_chirps
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: GetPinnedChirps :many
+SELECT chirps.* FROM chirps
+JOIN pinned_chirps ON pinned_chirps.chirp_id = chirps.id
+WHERE pinned_chirps.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL
+ORDER BY pinned_chirps.position ASC, pinned_chirps.created_at ASC;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+JOIN chirps ON chirps.id = pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id = $1
+AND chirps.deleted_at IS NULL
+AND chirps.hidden_at IS NULL;
+
+-- name: SetPinPosition :execrows
+UPDATE pinned_chirps
+SET position = $3
+WHERE user_id = $1
+AND chirp_id = $2;
@@ -0,0 +1,64 @@
+-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, closes_at)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, text)
+VALUES ($1, $2, $3);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPollsByChirpIDs :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: GetPollOptionsByChirpIDs :many
+SELECT * FROM poll_options
+WHERE chirp_id = ANY(@chirp_ids::UUID[])
+ORDER BY chirp_id, position;
+
+-- name: GetPollVotesByUser :many
+SELECT * FROM poll_votes
+WHERE user_id = @user_id
+AND chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: GetPollVote :one
+SELECT * FROM poll_votes
+WHERE chirp_id = $1
+AND user_id = $2;
+
+-- name: VotePoll :execrows
+-- Affects no rows if the user has already voted or the poll has closed.
+-- The vote and the count change together, so concurrent votes can't lose
+-- an update.
+WITH inserted AS (
+  INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+  SELECT polls.chirp_id, @user_id::UUID, @position::INTEGER, NOW()
+  FROM polls
+  WHERE polls.chirp_id = @chirp_id::UUID
+  AND polls.closes_at > NOW()
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id, position
+)
+UPDATE poll_options
+SET vote_count = vote_count + 1
+FROM inserted
+WHERE poll_options.chirp_id = inserted.chirp_id
+AND poll_options.position = inserted.position;
+
+-- name: RemovePollVotesOfUsersPastGracePeriod :exec
+UPDATE poll_options
+SET vote_count = poll_options.vote_count - purged.votes
+FROM (
+  SELECT poll_votes.chirp_id, poll_votes.position, COUNT(*) AS votes
+  FROM poll_votes
+  JOIN users ON users.id = poll_votes.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  GROUP BY poll_votes.chirp_id, poll_votes.position
+) AS purged
+WHERE poll_options.chirp_id = purged.chirp_id
+AND poll_options.position = purged.position;
@@ -0,0 +1,50 @@
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  '',
+  @user_id,
+  @rechirp_of_id::UUID
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
+SELECT * FROM chirps
+WHERE user_id = @user_id
+AND rechirp_of_id = @rechirp_of_id::UUID;
+
+-- name: GetRechirpedChirpIDs :many
+SELECT rechirp_of_id::UUID FROM chirps
+WHERE user_id = @user_id
+AND rechirp_of_id = ANY(@chirp_ids::UUID[]);
+
+-- name: UpdateRechirpCount :exec
+UPDATE chirps
+SET rechirp_count = rechirp_count + @delta
+WHERE id = @id;
+
+-- name: UpdateQuoteCount :exec
+UPDATE chirps
+SET quote_count = quote_count + @delta
+WHERE id = @id;
+
+-- name: RemoveSharesOfUsersPastGracePeriod :exec
+UPDATE chirps
+SET rechirp_count = chirps.rechirp_count - purged.rechirps,
+  quote_count = chirps.quote_count - purged.quotes
+FROM (
+  SELECT COALESCE(shares.rechirp_of_id, shares.quoted_chirp_id) AS chirp_id,
+    COUNT(shares.rechirp_of_id) AS rechirps,
+    COUNT(shares.quoted_chirp_id) AS quotes
+  FROM chirps AS shares
+  JOIN users ON users.id = shares.user_id
+  WHERE users.delete_after IS NOT NULL
+  AND users.delete_after <= NOW()
+  AND (shares.rechirp_of_id IS NOT NULL OR shares.quoted_chirp_id IS NOT NULL)
+  AND shares.deleted_at IS NULL
+  GROUP BY 1
+) AS purged
+WHERE chirps.id = purged.chirp_id;
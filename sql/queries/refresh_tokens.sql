@@ -27,4 +27,15 @@ AND refresh_tokens.expires_at > NOW();
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetRefreshTokensByUserID :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
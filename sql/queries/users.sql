@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username)
 VALUES (
   gen_random_uuid(),
   NOW(),
   NOW(),
   $1,
-  $2
+  $2,
+  $3
 )
 RETURNING *;
 
@@ -17,9 +18,14 @@ WHERE email=$1;
 SELECT * FROM users
 WHERE id=$1;
 
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE id=$1
+FOR UPDATE;
+
 -- name: UpdateUser :one
 UPDATE users
-SET email = $2, hashed_password = $3, updated_at = NOW()
+SET email = $2, hashed_password = $3, username = COALESCE(sqlc.narg(username), username), updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
@@ -27,4 +33,88 @@ RETURNING *;
 UPDATE users
 SET is_chirpy_red = TRUE, updated_at = NOW()
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET delete_after = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET delete_after = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteUsersPastGracePeriod :execrows
+DELETE FROM users
+WHERE delete_after IS NOT NULL
+AND delete_after <= NOW();
+
+
+-- name: GetUsersByUsernames :many
+SELECT * FROM users
+WHERE username = ANY(@usernames::TEXT[]);
+
+-- name: SearchUsers :many
+-- Matches query anywhere in the email or username, ignoring case. An empty
+-- query matches everyone.
+SELECT * FROM users
+WHERE @query::TEXT = ''
+OR strpos(lower(email), lower(@query::TEXT)) > 0
+OR strpos(lower(username), lower(@query::TEXT)) > 0
+ORDER BY created_at DESC
+LIMIT @max_results;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2, suspension_reason = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_until = NULL, suspension_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: BanUser :one
+UPDATE users
+SET banned_at = COALESCE(banned_at, NOW()), ban_reason = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users
+SET banned_at = NULL, ban_reason = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetChirpyRed :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserProtected :one
+UPDATE users
+SET protected = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetProtectedUserIDs :many
+SELECT id FROM users
+WHERE id = ANY(@ids::UUID[])
+AND protected;
+
+-- name: SetUserDMsFollowersOnly :one
+UPDATE users
+SET dms_followers_only = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
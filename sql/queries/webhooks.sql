@@ -0,0 +1,140 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, user_id, url, secret, event_types)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: GetWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE user_id IS NOT DISTINCT FROM @owner_id
+ORDER BY created_at ASC;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = @id
+AND user_id IS NOT DISTINCT FROM @owner_id;
+
+-- name: GetWebhookSubscriptionByID :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = @url,
+  event_types = @event_types,
+  enabled = @enabled,
+  consecutive_failures = CASE WHEN @enabled::BOOLEAN THEN 0 ELSE consecutive_failures END,
+  disabled_at = CASE
+    WHEN @enabled::BOOLEAN THEN NULL
+    ELSE COALESCE(disabled_at, NOW())
+  END,
+  updated_at = NOW()
+WHERE id = @id
+AND user_id IS NOT DISTINCT FROM @owner_id
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = @id
+AND user_id IS NOT DISTINCT FROM @owner_id;
+
+-- name: RecordWebhookSuccess :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+-- Counts a failed attempt against the subscription, disabling it once
+-- @max_failures attempts in a row have failed.
+UPDATE webhook_subscriptions
+SET consecutive_failures = consecutive_failures + 1,
+  enabled = enabled AND consecutive_failures + 1 < @max_failures::INTEGER,
+  disabled_at = CASE
+    WHEN enabled AND consecutive_failures + 1 >= @max_failures::INTEGER THEN NOW()
+    ELSE disabled_at
+  END
+WHERE id = @id
+RETURNING *;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (id, created_at, subscription_id, event_type, payload, next_attempt_at, event_key)
+SELECT gen_random_uuid(), NOW(), id, @event_type, @payload, NOW(), @event_key::TEXT
+FROM webhook_subscriptions
+WHERE enabled
+AND @event_type = ANY(event_types)
+AND (user_id IS NULL OR user_id = @user_id::UUID)
+ON CONFLICT (subscription_id, event_key) DO NOTHING;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Leases due deliveries by pushing next_attempt_at past the time it takes
+-- to send them, so a crashed worker's deliveries are picked up again.
+-- leased_until marks them as being sent until the attempt is recorded.
+UPDATE webhook_deliveries
+SET next_attempt_at = @lease_until, leased_until = @lease_until
+WHERE id IN (
+  SELECT d.id FROM webhook_deliveries d
+  JOIN webhook_subscriptions s ON s.id = d.subscription_id
+  WHERE d.status = 'pending'
+  AND d.next_attempt_at <= NOW()
+  AND s.enabled
+  ORDER BY d.next_attempt_at ASC
+  LIMIT @max_results
+  FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, created_at, delivery_id, status_code, error, duration_ms)
+VALUES (
+  gen_random_uuid(),
+  NOW(),
+  $1,
+  $2,
+  $3,
+  $4
+);
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded', attempts = attempts + 1, delivered_at = NOW(), leased_until = NULL
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+-- Records a failed attempt. The delivery is retried at next_attempt_at
+-- unless status gives up on it.
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, leased_until = NULL
+WHERE id = $1;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = ANY(@delivery_ids::UUID[])
+ORDER BY created_at ASC;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1
+AND subscription_id = $2;
+
+-- name: RedeliverWebhookDelivery :one
+-- Returns no rows while a worker holds the delivery, so it isn't sent
+-- twice at once.
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+WHERE id = $1
+AND subscription_id = $2
+AND (leased_until IS NULL OR leased_until <= NOW())
+RETURNING *;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE moderation_terms (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('word', 'regex')),
+  pattern TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('mask', 'flag', 'reject')),
+  UNIQUE (kind, pattern)
+);
+
+CREATE TABLE chirp_flags (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reasons TEXT[] NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_terms;
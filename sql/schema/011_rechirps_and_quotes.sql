@@ -0,0 +1,23 @@
+-- +goose Up
+-- The originals aren't foreign keys: a rechirp or quote outlives the chirp
+-- it points at, so clients can show that the original was deleted.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of_id UUID,
+ADD COLUMN quoted_chirp_id UUID,
+ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN quote_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_idx ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+
+CREATE INDEX chirps_quoted_chirp_id_idx ON chirps (quoted_chirp_id);
+
+-- +goose Down
+DROP INDEX chirps_quoted_chirp_id_idx;
+DROP INDEX chirps_user_id_rechirp_of_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN quote_count,
+DROP COLUMN rechirp_count,
+DROP COLUMN quoted_chirp_id,
+DROP COLUMN rechirp_of_id;
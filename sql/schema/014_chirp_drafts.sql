@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE chirp_drafts (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body TEXT NOT NULL,
+  quoted_chirp_id UUID,
+  publish_at TIMESTAMP,
+  publish_error TEXT
+);
+
+CREATE INDEX chirp_drafts_user_id_idx ON chirp_drafts (user_id);
+
+CREATE INDEX chirp_drafts_publish_at_idx ON chirp_drafts (publish_at)
+WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE chirp_drafts;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE media (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  content_type TEXT NOT NULL,
+  byte_size INTEGER NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  storage_key TEXT NOT NULL UNIQUE,
+  thumbnail_key TEXT NOT NULL UNIQUE,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+  position INTEGER,
+  alt_text TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX media_chirp_id_position_idx ON media (chirp_id, position);
+
+CREATE INDEX media_unattached_idx ON media (created_at)
+WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP TABLE media;
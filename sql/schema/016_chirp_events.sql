@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE SEQUENCE chirp_event_seq;
+
+-- +goose Down
+DROP SEQUENCE chirp_event_seq;
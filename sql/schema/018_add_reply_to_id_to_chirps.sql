@@ -0,0 +1,13 @@
+-- +goose Up
+-- Like quotes, a reply keeps pointing at its parent after the parent is
+-- deleted.
+ALTER TABLE chirps
+ADD COLUMN reply_to_id UUID;
+
+CREATE INDEX chirps_reply_to_id_idx ON chirps (reply_to_id, created_at);
+
+-- +goose Down
+DROP INDEX chirps_reply_to_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN reply_to_id;
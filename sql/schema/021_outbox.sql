@@ -0,0 +1,33 @@
+-- +goose Up
+-- Domain events written in the same transaction as the change they
+-- describe, and relayed to subscribers once it has committed.
+CREATE TABLE outbox (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL,
+  last_error TEXT,
+  processed_at TIMESTAMP
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (created_at)
+WHERE processed_at IS NULL;
+
+-- Events can be relayed more than once, so whatever subscribers create
+-- records the event it came from and skips events it has already seen.
+ALTER TABLE notifications ADD COLUMN event_key TEXT;
+CREATE UNIQUE INDEX notifications_user_id_event_key_idx ON notifications (user_id, event_key);
+
+ALTER TABLE webhook_deliveries ADD COLUMN event_key TEXT;
+CREATE UNIQUE INDEX webhook_deliveries_subscription_id_event_key_idx ON webhook_deliveries (subscription_id, event_key);
+
+-- +goose Down
+DROP INDEX webhook_deliveries_subscription_id_event_key_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN event_key;
+
+DROP INDEX notifications_user_id_event_key_idx;
+ALTER TABLE notifications DROP COLUMN event_key;
+
+DROP TABLE outbox;
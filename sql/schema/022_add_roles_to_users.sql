@@ -0,0 +1,17 @@
+-- +goose Up
+-- There's no endpoint for making the first admin; promote them directly:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+ADD COLUMN suspended_until TIMESTAMP,
+ADD COLUMN suspension_reason TEXT,
+ADD COLUMN banned_at TIMESTAMP,
+ADD COLUMN ban_reason TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN ban_reason,
+DROP COLUMN banned_at,
+DROP COLUMN suspension_reason,
+DROP COLUMN suspended_until,
+DROP COLUMN role;
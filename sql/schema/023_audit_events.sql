@@ -0,0 +1,43 @@
+-- +goose Up
+-- actor_id and target_id aren't foreign keys: entries outlive what they
+-- refer to. id is assigned by the application, which hashes it into the
+-- chain along with metadata, kept as JSON rather than JSONB so it comes
+-- back byte for byte.
+CREATE TABLE audit_events (
+  id BIGINT PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  actor_id UUID,
+  action TEXT NOT NULL,
+  target_type TEXT NOT NULL,
+  target_id TEXT NOT NULL,
+  ip TEXT NOT NULL,
+  user_agent TEXT NOT NULL,
+  request_id TEXT NOT NULL,
+  metadata JSON NOT NULL,
+  prev_hash TEXT NOT NULL,
+  hash TEXT NOT NULL
+);
+
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, id DESC);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, id DESC);
+CREATE INDEX audit_events_action_idx ON audit_events (action, id DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update_or_delete
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+BEFORE TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP FUNCTION audit_events_append_only();
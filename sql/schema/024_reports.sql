@@ -0,0 +1,57 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+-- A case collects everything pointing at one chirp: user reports and
+-- moderation pipeline flags. A chirp has at most one unresolved case; new
+-- reports after it's resolved open another.
+CREATE TABLE moderation_cases (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  status TEXT NOT NULL CHECK (status IN ('open', 'claimed', 'resolved')),
+  claimed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  claimed_at TIMESTAMP,
+  resolution TEXT CHECK (resolution IN ('dismiss', 'hide_chirp', 'suspend_author')),
+  resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX moderation_cases_unresolved_chirp_id_idx ON moderation_cases (chirp_id)
+WHERE status <> 'resolved';
+
+CREATE INDEX moderation_cases_status_idx ON moderation_cases (status, created_at);
+
+CREATE TABLE reports (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  case_id UUID NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'self_harm', 'misinformation', 'other')),
+  details TEXT NOT NULL DEFAULT '',
+  UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX reports_case_id_idx ON reports (case_id);
+
+-- Every claim, release and resolution of a case, in order.
+CREATE TABLE moderation_decisions (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  case_id UUID NOT NULL REFERENCES moderation_cases(id) ON DELETE CASCADE,
+  moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL CHECK (action IN ('claim', 'release', 'dismiss', 'hide_chirp', 'suspend_author')),
+  note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX moderation_decisions_case_id_idx ON moderation_decisions (case_id, created_at);
+
+ALTER TABLE chirp_flags ADD COLUMN case_id UUID REFERENCES moderation_cases(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE chirp_flags DROP COLUMN case_id;
+DROP TABLE moderation_decisions;
+DROP TABLE reports;
+DROP TABLE moderation_cases;
+ALTER TABLE chirps DROP COLUMN hidden_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE muted_words (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('word', 'hashtag')),
+  pattern TEXT NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('hide', 'collapse')),
+  expires_at TIMESTAMP,
+  UNIQUE (user_id, kind, pattern)
+);
+
+-- +goose Down
+DROP TABLE muted_words;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN protected BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'followers', 'mentioned'));
+
+-- Follows of protected accounts wait here until they're approved.
+CREATE TABLE follow_requests (
+  requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (requester_id, target_id),
+  CHECK (requester_id <> target_id)
+);
+
+CREATE INDEX follow_requests_target_id_idx ON follow_requests (target_id, created_at);
+
+-- +goose Down
+DROP TABLE follow_requests;
+ALTER TABLE chirps DROP COLUMN visibility;
+ALTER TABLE users DROP COLUMN protected;
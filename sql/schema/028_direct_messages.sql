@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN dms_followers_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE conversations (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  -- One-to-one conversations are keyed by their two members so each pair
+  -- has only one. Groups have no key.
+  direct_key TEXT UNIQUE
+);
+
+-- Members have read every message sent up to last_read_at.
+CREATE TABLE conversation_members (
+  conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  joined_at TIMESTAMP NOT NULL,
+  last_read_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX conversation_members_user_id_idx ON conversation_members (user_id);
+
+CREATE TABLE messages (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+  sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body TEXT NOT NULL
+);
+
+CREATE INDEX messages_conversation_id_idx ON messages (conversation_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE messages;
+DROP TABLE conversation_members;
+DROP TABLE conversations;
+ALTER TABLE users DROP COLUMN dms_followers_only;
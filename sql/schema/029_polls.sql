@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE polls (
+  chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  closes_at TIMESTAMP NOT NULL
+);
+
+-- vote_count is kept in step with poll_votes by VotePoll.
+CREATE TABLE poll_options (
+  chirp_id UUID NOT NULL REFERENCES polls(chirp_id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  text TEXT NOT NULL,
+  vote_count INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (chirp_id, position)
+);
+
+-- The primary key gives each user one vote per poll.
+CREATE TABLE poll_votes (
+  chirp_id UUID NOT NULL REFERENCES polls(chirp_id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (chirp_id, user_id),
+  FOREIGN KEY (chirp_id, position) REFERENCES poll_options(chirp_id, position) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
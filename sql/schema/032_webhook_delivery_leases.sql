@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE webhook_deliveries
+ADD COLUMN leased_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webhook_deliveries
+DROP COLUMN leased_until;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirp_drafts
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'followers', 'mentioned'));
+
+-- +goose Down
+ALTER TABLE chirp_drafts
+DROP COLUMN visibility;
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chonginator/chirpy/internal/database"
+	"github.com/chonginator/chirpy/internal/feed"
+	"github.com/google/uuid"
+)
+
+// chirpViewer loads what decides which chirps viewerID can see. Anonymous
+// viewers get the zero feed.Viewer.
+func chirpViewer(ctx context.Context, q *database.Queries, viewerID uuid.UUID) (feed.Viewer, error) {
+	if viewerID == uuid.Nil {
+		return feed.Viewer{}, nil
+	}
+
+	followeeIDs, err := q.GetFolloweeIDs(ctx, viewerID)
+	if err != nil {
+		return feed.Viewer{}, err
+	}
+
+	viewer := feed.Viewer{ID: viewerID, Following: map[uuid.UUID]bool{}}
+	for _, id := range followeeIDs {
+		viewer.Following[id] = true
+	}
+	return viewer, nil
+}
+
+// viewableChirps returns the chirps in dbChirps that viewer can see, in
+// the same order. Anything else must be treated as if it doesn't exist.
+func viewableChirps(ctx context.Context, q *database.Queries, viewer feed.Viewer, dbChirps []database.Chirp) ([]database.Chirp, error) {
+	authorIDs := []uuid.UUID{}
+	mentionedChirpIDs := []uuid.UUID{}
+	for _, chirp := range dbChirps {
+		authorIDs = append(authorIDs, chirp.UserID)
+		if feed.Visibility(chirp.Visibility) == feed.Mentioned {
+			mentionedChirpIDs = append(mentionedChirpIDs, chirp.ID)
+		}
+	}
+	if len(authorIDs) == 0 {
+		return []database.Chirp{}, nil
+	}
+
+	protectedIDs, err := q.GetProtectedUserIDs(ctx, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	protected := map[uuid.UUID]bool{}
+	for _, id := range protectedIDs {
+		protected[id] = true
+	}
+
+	mentioned := map[uuid.UUID][]uuid.UUID{}
+	if len(mentionedChirpIDs) > 0 {
+		mentions, err := q.GetChirpMentionsByChirpIDs(ctx, mentionedChirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, mention := range mentions {
+			if mention.UserID.Valid {
+				mentioned[mention.ChirpID] = append(mentioned[mention.ChirpID], mention.UserID.UUID)
+			}
+		}
+	}
+
+	viewable := []database.Chirp{}
+	for _, chirp := range dbChirps {
+		if viewer.CanView(feed.Audience{
+			AuthorID:   chirp.UserID,
+			Visibility: feed.Visibility(chirp.Visibility),
+			Protected:  protected[chirp.UserID],
+			Mentioned:  mentioned[chirp.ID],
+			Hidden:     chirp.HiddenAt.Valid,
+		}) {
+			viewable = append(viewable, chirp)
+		}
+	}
+	return viewable, nil
+}
+
+// canViewChirp reports whether viewerID can see chirp. Only its author can
+// see a chirp moderators have hidden, and nobody sees a chirp by someone
+// they've blocked or who blocked them. Handlers respond to a chirp the
+// viewer can't see exactly as to one that doesn't exist, so its existence
+// can't be discovered.
+func canViewChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, viewerID uuid.UUID) (bool, error) {
+	viewer, err := chirpViewer(ctx, q, viewerID)
+	if err != nil {
+		return false, err
+	}
+	viewable, err := viewableChirps(ctx, q, viewer, []database.Chirp{chirp})
+	if err != nil {
+		return false, err
+	}
+	if len(viewable) == 0 || viewerID == uuid.Nil {
+		return len(viewable) == 1, nil
+	}
+
+	blocked, err := q.IsBlocked(ctx, database.IsBlockedParams{
+		UserID:  viewerID,
+		OtherID: chirp.UserID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return !blocked, nil
+}
+
+// isPublicChirp reports whether anyone at all can see chirp, which is what
+// it takes to rechirp it or send it to the public chirp stream.
+func isPublicChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) (bool, error) {
+	return canViewChirp(ctx, q, chirp, uuid.Nil)
+}
+
+// getViewableChirp is GetChirpByID for viewerID, returning sql.ErrNoRows
+// for a chirp they can't see.
+func getViewableChirp(ctx context.Context, q *database.Queries, chirpID, viewerID uuid.UUID) (database.Chirp, error) {
+	chirp, err := q.GetChirpByID(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	viewable, err := canViewChirp(ctx, q, chirp, viewerID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !viewable {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}